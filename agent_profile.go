@@ -1,5 +1,11 @@
 package manusai
 
+import (
+	"context"
+	"sync"
+	"time"
+)
+
 const (
 	AgentProfileManus16     = "manus-1.6"
 	AgentProfileManus16Lite = "manus-1.6-lite"
@@ -8,42 +14,254 @@ const (
 	AgentProfileQuality     = "quality"
 )
 
-var (
-	allProfiles = []string{
-		AgentProfileManus16,
-		AgentProfileManus16Lite,
-		AgentProfileManus16Max,
-		AgentProfileSpeed,
-		AgentProfileQuality,
+const (
+	DefaultAgentProfileTTL = time.Hour
+	// agentProfileRetryInterval limits how often a failing source is retried.
+	agentProfileRetryInterval = time.Minute
+)
+
+// ProfileTier ranks profiles relative to each other; higher means more
+// expensive or faster.
+type ProfileTier int
+
+const (
+	ProfileTierLow ProfileTier = iota + 1
+	ProfileTierMedium
+	ProfileTierHigh
+)
+
+type AgentProfileInfo struct {
+	Name       string `json:"name"`
+	Deprecated bool   `json:"deprecated"`
+	// Replacement is the profile to use instead of a deprecated one.
+	Replacement string      `json:"replacement,omitempty"`
+	CostTier    ProfileTier `json:"cost_tier"`
+	SpeedTier   ProfileTier `json:"speed_tier"`
+}
+
+var builtinProfiles = []AgentProfileInfo{
+	{Name: AgentProfileManus16, CostTier: ProfileTierMedium, SpeedTier: ProfileTierMedium},
+	{Name: AgentProfileManus16Lite, CostTier: ProfileTierLow, SpeedTier: ProfileTierHigh},
+	{Name: AgentProfileManus16Max, CostTier: ProfileTierHigh, SpeedTier: ProfileTierLow},
+	{Name: AgentProfileSpeed, Deprecated: true, Replacement: AgentProfileManus16Lite, CostTier: ProfileTierLow, SpeedTier: ProfileTierHigh},
+	{Name: AgentProfileQuality, Deprecated: true, Replacement: AgentProfileManus16, CostTier: ProfileTierMedium, SpeedTier: ProfileTierMedium},
+}
+
+// BuiltinAgentProfiles returns the profiles known to this SDK release, used
+// when no registry has loaded a list from its source.
+func BuiltinAgentProfiles() []AgentProfileInfo {
+	return append([]AgentProfileInfo(nil), builtinProfiles...)
+}
+
+type AgentProfileSource interface {
+	FetchAgentProfiles(ctx context.Context) ([]AgentProfileInfo, error)
+}
+
+type AgentProfileSourceFunc func(ctx context.Context) ([]AgentProfileInfo, error)
+
+func (f AgentProfileSourceFunc) FetchAgentProfiles(ctx context.Context) ([]AgentProfileInfo, error) {
+	return f(ctx)
+}
+
+// AgentProfileRegistry caches profiles from a source for a TTL. While the
+// source has never answered, the built-in list is used; after that, a
+// failed refresh keeps the last list.
+type AgentProfileRegistry struct {
+	source AgentProfileSource
+	ttl    time.Duration
+	now    func() time.Time
+
+	// fetchMu serialises refreshes so readers are not blocked by the source.
+	fetchMu sync.Mutex
+
+	mu        sync.Mutex
+	profiles  []AgentProfileInfo
+	refreshAt time.Time
+	lastErr   error
+}
+
+func NewAgentProfileRegistry(source AgentProfileSource, ttl time.Duration) *AgentProfileRegistry {
+	if ttl <= 0 {
+		ttl = DefaultAgentProfileTTL
+	}
+	return &AgentProfileRegistry{source: source, ttl: ttl, now: time.Now}
+}
+
+// Profiles returns the cached profiles, refreshing them from the source once
+// the TTL has passed. Source errors are not returned; see LastError.
+func (r *AgentProfileRegistry) Profiles(ctx context.Context) []AgentProfileInfo {
+	if r.source != nil && r.due() {
+		r.fetchMu.Lock()
+		if r.due() {
+			r.refresh(ctx)
+		}
+		r.fetchMu.Unlock()
+	}
+	return r.Snapshot()
+}
+
+func (r *AgentProfileRegistry) due() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.now().Before(r.refreshAt)
+}
+
+func (r *AgentProfileRegistry) refresh(ctx context.Context) {
+	profiles, err := r.source.FetchAgentProfiles(ctx)
+	if err == nil && len(profiles) == 0 {
+		err = &ManusAIError{Message: "Agent profile source returned no profiles"}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if err != nil {
+		retry := agentProfileRetryInterval
+		if retry > r.ttl {
+			retry = r.ttl
+		}
+		r.refreshAt = now.Add(retry)
+		r.lastErr = err
+		return
 	}
+	r.profiles = append([]AgentProfileInfo(nil), profiles...)
+	r.refreshAt = now.Add(r.ttl)
+	r.lastErr = nil
+}
 
-	recommendedProfiles = []string{
-		AgentProfileManus16,
-		AgentProfileManus16Lite,
-		AgentProfileManus16Max,
+// Lookup finds a profile by name in the cached list without refreshing it.
+func (r *AgentProfileRegistry) Lookup(name string) (AgentProfileInfo, bool) {
+	for _, p := range r.Snapshot() {
+		if p.Name == name {
+			return p, true
+		}
 	}
+	return AgentProfileInfo{}, false
+}
 
-	deprecatedProfiles = []string{
-		AgentProfileSpeed,
-		AgentProfileQuality,
+// Snapshot returns the cached profiles, or the built-in list if nothing has
+// been loaded, without contacting the source.
+func (r *AgentProfileRegistry) Snapshot() []AgentProfileInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshotLocked()
+}
+
+func (r *AgentProfileRegistry) snapshotLocked() []AgentProfileInfo {
+	if r.profiles == nil {
+		return BuiltinAgentProfiles()
 	}
+	return append([]AgentProfileInfo(nil), r.profiles...)
+}
+
+// LastError returns the error from the most recent failed refresh, or nil.
+func (r *AgentProfileRegistry) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// Invalidate makes the next Profiles call refresh from the source.
+func (r *AgentProfileRegistry) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshAt = time.Time{}
+}
+
+var (
+	defaultRegistryMu sync.RWMutex
+	defaultRegistry   = NewAgentProfileRegistry(nil, 0)
 )
 
+// SetDefaultAgentProfileRegistry sets the registry consulted by the
+// package-level profile functions, typically Client.AgentProfileRegistry().
+// Passing nil restores the built-in list.
+//
+// The package-level functions take no context and never contact the source:
+// they read the registry's Snapshot, which is the built-in list until
+// something such as Client.ListAgentProfiles or CreateTask has loaded it.
+func SetDefaultAgentProfileRegistry(registry *AgentProfileRegistry) {
+	if registry == nil {
+		registry = NewAgentProfileRegistry(nil, 0)
+	}
+	defaultRegistryMu.Lock()
+	defaultRegistry = registry
+	defaultRegistryMu.Unlock()
+}
+
+func defaultProfiles() []AgentProfileInfo {
+	defaultRegistryMu.RLock()
+	registry := defaultRegistry
+	defaultRegistryMu.RUnlock()
+	return registry.Snapshot()
+}
+
+// WithAgentProfileRegistry sets the client's registry. Without it, or
+// WithAgentProfilesFromAPI, the client uses the built-in list.
+func WithAgentProfileRegistry(registry *AgentProfileRegistry) ClientOption {
+	return func(c *Client) {
+		c.profiles = registry
+	}
+}
+
+// WithAgentProfilesFromAPI loads the client's profile list from the
+// GET /v1/agent-profiles endpoint, caching it for ttl. The endpoint may not
+// be available on every deployment; the built-in list is used until it
+// answers.
+func WithAgentProfilesFromAPI(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.profiles = NewAgentProfileRegistry(c, ttl)
+	}
+}
+
+func (c *Client) AgentProfileRegistry() *AgentProfileRegistry {
+	return c.profiles
+}
+
+// ListAgentProfiles returns the profiles from the client's registry,
+// refreshing them from its source if one is set. By default there is no
+// source and this is the built-in list.
+func (c *Client) ListAgentProfiles(ctx context.Context) []AgentProfileInfo {
+	return c.profiles.Profiles(ctx)
+}
+
+// FetchAgentProfiles loads the profile list from the API, bypassing the
+// registry cache. It is the source used by WithAgentProfilesFromAPI.
+func (c *Client) FetchAgentProfiles(ctx context.Context) ([]AgentProfileInfo, error) {
+	var result struct {
+		Data []AgentProfileInfo `json:"data"`
+	}
+	if err := c.requestContext(ctx, "GET", "/v1/agent-profiles", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// AllAgentProfiles returns every profile name in the default registry's
+// cached list.
 func AllAgentProfiles() []string {
-	result := make([]string, len(allProfiles))
-	copy(result, allProfiles)
+	var result []string
+	for _, p := range defaultProfiles() {
+		result = append(result, p.Name)
+	}
 	return result
 }
 
+// RecommendedAgentProfiles returns the profiles in the default registry that
+// are not deprecated.
 func RecommendedAgentProfiles() []string {
-	result := make([]string, len(recommendedProfiles))
-	copy(result, recommendedProfiles)
+	var result []string
+	for _, p := range defaultProfiles() {
+		if !p.Deprecated {
+			result = append(result, p.Name)
+		}
+	}
 	return result
 }
 
 func IsValidAgentProfile(profile string) bool {
-	for _, p := range allProfiles {
-		if p == profile {
+	for _, p := range defaultProfiles() {
+		if p.Name == profile {
 			return true
 		}
 	}
@@ -51,9 +269,9 @@ func IsValidAgentProfile(profile string) bool {
 }
 
 func IsDeprecatedAgentProfile(profile string) bool {
-	for _, p := range deprecatedProfiles {
-		if p == profile {
-			return true
+	for _, p := range defaultProfiles() {
+		if p.Name == profile {
+			return p.Deprecated
 		}
 	}
 	return false
@@ -1,9 +1,15 @@
 package manusai
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAllAgentProfiles(t *testing.T) {
@@ -66,3 +72,99 @@ func TestIsDeprecatedAgentProfile(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentProfileRegistry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	var fail bool
+	source := AgentProfileSourceFunc(func(ctx context.Context) ([]AgentProfileInfo, error) {
+		calls++
+		if fail {
+			return nil, errors.New("offline")
+		}
+		return []AgentProfileInfo{{Name: "manus-2.0", CostTier: ProfileTierMedium, SpeedTier: ProfileTierHigh}}, nil
+	})
+
+	registry := NewAgentProfileRegistry(source, time.Hour)
+	registry.now = func() time.Time { return now }
+
+	fail = true
+	profiles := registry.Profiles(context.Background())
+	assert.Len(t, profiles, 5, "falls back to the built-in list")
+	assert.EqualError(t, registry.LastError(), "offline")
+
+	registry.Profiles(context.Background())
+	assert.Equal(t, 1, calls, "failures are not retried immediately")
+
+	fail = false
+	now = now.Add(agentProfileRetryInterval)
+	profiles = registry.Profiles(context.Background())
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "manus-2.0", profiles[0].Name)
+	assert.NoError(t, registry.LastError())
+
+	now = now.Add(30 * time.Minute)
+	registry.Profiles(context.Background())
+	assert.Equal(t, 2, calls, "cached within the TTL")
+
+	fail = true
+	now = now.Add(time.Hour)
+	profiles = registry.Profiles(context.Background())
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "manus-2.0", profiles[0].Name, "keeps the last list when a refresh fails")
+
+	info, ok := registry.Lookup("manus-2.0")
+	assert.True(t, ok)
+	assert.Equal(t, ProfileTierHigh, info.SpeedTier)
+}
+
+func TestDefaultAgentProfileRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/agent-profiles", r.URL.Path)
+		w.Write([]byte(`{"data":[
+			{"name":"manus-1.6","cost_tier":2,"speed_tier":2},
+			{"name":"manus-1.6-lite","deprecated":true,"replacement":"manus-2.0-lite","cost_tier":1,"speed_tier":3},
+			{"name":"manus-2.0-lite","cost_tier":1,"speed_tier":3}
+		]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithAgentProfilesFromAPI(0))
+	require.NoError(t, err)
+
+	SetDefaultAgentProfileRegistry(client.AgentProfileRegistry())
+	defer SetDefaultAgentProfileRegistry(nil)
+
+	assert.False(t, IsDeprecatedAgentProfile(AgentProfileManus16Lite), "nothing loaded yet")
+
+	profiles := client.ListAgentProfiles(context.Background())
+	require.Len(t, profiles, 3)
+	assert.Equal(t, "manus-2.0-lite", profiles[1].Replacement)
+
+	assert.True(t, IsValidAgentProfile("manus-2.0-lite"))
+	assert.True(t, IsDeprecatedAgentProfile(AgentProfileManus16Lite))
+	assert.False(t, IsValidAgentProfile(AgentProfileSpeed))
+	assert.Equal(t, []string{AgentProfileManus16, "manus-2.0-lite"}, RecommendedAgentProfiles())
+}
+
+func TestClientAgentProfilesOffline(t *testing.T) {
+	client, err := NewClient("test-key", WithBaseURL("http://127.0.0.1:1"), WithAgentProfilesFromAPI(0))
+	require.NoError(t, err)
+
+	profiles := client.ListAgentProfiles(context.Background())
+	assert.Equal(t, BuiltinAgentProfiles(), profiles)
+	assert.Error(t, client.AgentProfileRegistry().LastError())
+}
+
+func TestClientAgentProfilesDefaultToBuiltin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	assert.Equal(t, BuiltinAgentProfiles(), client.ListAgentProfiles(context.Background()))
+	assert.NoError(t, client.AgentProfileRegistry().LastError())
+}
@@ -0,0 +1,417 @@
+package manusai
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+const DefaultArchiveManifestName = "manifest.json"
+
+type ArchiveOptions struct {
+	// Format defaults to ArchiveZip.
+	Format ArchiveFormat
+	// Name is the uploaded file name, "archive.zip" or "archive.tar.gz" by
+	// default.
+	Name string
+	// Exclude holds .gitignore-style patterns: "*.log" matches at any depth,
+	// "/build" or "docs/*.md" are relative to the source root, a trailing
+	// slash matches only directories and "!" re-includes a file.
+	Exclude []string
+	// IgnoreFile, e.g. ".gitignore", is read from the root of each source
+	// directory and its patterns are added to Exclude.
+	IgnoreFile string
+	// ManifestName defaults to DefaultArchiveManifestName.
+	ManifestName string
+	NoManifest   bool
+	Upload       *UploadOptions
+}
+
+// ArchiveManifest is written into the archive as JSON, after the files it
+// lists.
+type ArchiveManifest struct {
+	Files []ArchiveManifestEntry `json:"files"`
+}
+
+type ArchiveManifestEntry struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256"`
+}
+
+type archiveEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	open    func() (io.ReadCloser, error)
+}
+
+// NewArchiveAttachment packs files into a single zip or tar.gz, uploads it
+// and returns a file_id attachment once the file is ready. The source is a
+// directory path, a []string of file and directory paths, or an fs.FS.
+//
+// The archive is generated on the fly, without a temporary file: once to
+// measure its size and again for each upload attempt, so the files must not
+// change during the upload.
+func NewArchiveAttachment(ctx context.Context, client *Client, source interface{}, options *ArchiveOptions) (map[string]interface{}, error) {
+	var opts ArchiveOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.Format == "" {
+		opts.Format = ArchiveZip
+	}
+	if opts.Format != ArchiveZip && opts.Format != ArchiveTarGz {
+		return nil, &ValidationError{Message: fmt.Sprintf("Unsupported archive format %q", opts.Format)}
+	}
+	if opts.Name == "" {
+		opts.Name = "archive." + string(opts.Format)
+	}
+	if opts.ManifestName == "" && !opts.NoManifest {
+		opts.ManifestName = DefaultArchiveManifestName
+	}
+
+	entries, err := archiveEntriesFrom(source, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, &ValidationError{Message: "Archive has no files"}
+	}
+
+	open := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeArchive(pw, entries, &opts))
+		}()
+		return pr, nil
+	}
+
+	size, err := archiveSize(open)
+	if err != nil {
+		return nil, err
+	}
+
+	var uploadOpts UploadOptions
+	if opts.Upload != nil {
+		uploadOpts = *opts.Upload
+	}
+	if uploadOpts.ContentType == "" {
+		uploadOpts.ContentType = "application/zip"
+		if opts.Format == ArchiveTarGz {
+			uploadOpts.ContentType = "application/gzip"
+		}
+	}
+
+	file, err := client.createFile(ctx, opts.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.UploadFileReader(ctx, file.UploadURL, open, size, &uploadOpts); err != nil {
+		client.discardFiles([]string{file.ID})
+		return nil, err
+	}
+	if _, err := client.WaitForFile(ctx, file.ID); err != nil {
+		client.discardFiles([]string{file.ID})
+		return nil, err
+	}
+	return NewAttachmentFromFileID(file.ID), nil
+}
+
+func archiveSize(open func() (io.ReadCloser, error)) (int64, error) {
+	r, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	size, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return 0, &ManusAIError{Message: fmt.Sprintf("Failed to build archive: %v", err), Err: err}
+	}
+	return size, nil
+}
+
+func archiveEntriesFrom(source interface{}, opts *ArchiveOptions) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	var err error
+
+	switch v := source.(type) {
+	case string:
+		entries, err = archivePaths([]string{v}, opts, false)
+	case []string:
+		entries, err = archivePaths(v, opts, true)
+	case fs.FS:
+		entries, err = archiveFS(v, "", opts)
+	default:
+		return nil, &ValidationError{Message: fmt.Sprintf("Unsupported archive source type %T", source)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	for i, e := range entries {
+		if (i > 0 && entries[i-1].name == e.name) || e.name == opts.ManifestName {
+			return nil, &ValidationError{Message: fmt.Sprintf("Duplicate archive entry %q", e.name)}
+		}
+	}
+	return entries, nil
+}
+
+// archivePaths archives each path. With prefixDirs, a directory's files are
+// stored under the directory's base name; otherwise at the archive root.
+func archivePaths(paths []string, opts *ArchiveOptions, prefixDirs bool) ([]archiveEntry, error) {
+	rules := parseIgnoreRules(opts.Exclude)
+	var entries []archiveEntry
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, &ValidationError{Message: fmt.Sprintf("Cannot read archive source: %v", err), Err: err}
+		}
+
+		if info.IsDir() {
+			prefix := ""
+			if prefixDirs {
+				prefix = filepath.Base(filepath.Clean(p))
+			}
+			dirEntries, err := archiveFS(os.DirFS(p), prefix, opts)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, dirEntries...)
+			continue
+		}
+
+		name := filepath.Base(p)
+		if rules.excluded(name, false) {
+			continue
+		}
+		filePath := p
+		entries = append(entries, archiveEntry{
+			name:    name,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			open:    func() (io.ReadCloser, error) { return os.Open(filePath) },
+		})
+	}
+	return entries, nil
+}
+
+func archiveFS(fsys fs.FS, prefix string, opts *ArchiveOptions) ([]archiveEntry, error) {
+	patterns := opts.Exclude
+	if opts.IgnoreFile != "" {
+		data, err := fs.ReadFile(fsys, opts.IgnoreFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, &ValidationError{Message: fmt.Sprintf("Cannot read ignore file: %v", err), Err: err}
+		}
+		patterns = append(append([]string(nil), patterns...), strings.Split(string(data), "\n")...)
+	}
+	rules := parseIgnoreRules(patterns)
+
+	var entries []archiveEntry
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		if rules.excluded(p, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name := p
+		entries = append(entries, archiveEntry{
+			name:    path.Join(prefix, p),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			open:    func() (io.ReadCloser, error) { return fsys.Open(name) },
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, &ValidationError{Message: fmt.Sprintf("Cannot read archive source: %v", err), Err: err}
+	}
+	return entries, nil
+}
+
+// writeArchive writes entries, then the manifest, to w. The output depends
+// only on the entries, so it can be regenerated byte for byte.
+func writeArchive(w io.Writer, entries []archiveEntry, opts *ArchiveOptions) error {
+	bw := bufio.NewWriterSize(w, 64<<10)
+	manifest := ArchiveManifest{Files: make([]ArchiveManifestEntry, 0, len(entries))}
+	var latest time.Time
+
+	var add func(name string, size int64, modTime time.Time, r io.Reader) error
+	var finish func() error
+
+	switch opts.Format {
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(bw)
+		tw := tar.NewWriter(gz)
+		add = func(name string, size int64, modTime time.Time, r io.Reader) error {
+			hdr := &tar.Header{Name: name, Size: size, Mode: 0o644, ModTime: modTime, Typeflag: tar.TypeReg, Format: tar.FormatPAX}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := io.Copy(tw, r)
+			return err
+		}
+		finish = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return gz.Close()
+		}
+	default:
+		zw := zip.NewWriter(bw)
+		add = func(name string, size int64, modTime time.Time, r io.Reader) error {
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(fw, r)
+			return err
+		}
+		finish = zw.Close
+	}
+
+	for _, e := range entries {
+		src, err := e.open()
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		// Stop at the recorded size so a file growing between passes cannot
+		// corrupt the tar stream; a shrinking one fails below.
+		counted := &countingReader{r: io.TeeReader(io.LimitReader(src, e.size), h)}
+		err = add(e.name, e.size, e.modTime, counted)
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+		if counted.n != e.size {
+			return fmt.Errorf("%s: file changed while archiving", e.name)
+		}
+
+		manifest.Files = append(manifest.Files, ArchiveManifestEntry{Path: e.name, SizeBytes: e.size, SHA256: hex.EncodeToString(h.Sum(nil))})
+		if e.modTime.After(latest) {
+			latest = e.modTime
+		}
+	}
+
+	if !opts.NoManifest {
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := add(opts.ManifestName, int64(len(data)), latest, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+
+	if err := finish(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type ignoreRule struct {
+	pattern  string
+	segments []string // set for patterns anchored to the root
+	negate   bool
+	dirOnly  bool
+}
+
+type ignoreRules []ignoreRule
+
+func parseIgnoreRules(lines []string) ignoreRules {
+	var rules ignoreRules
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// excluded applies the rules to a slash-separated path relative to the
+// source root; the last matching rule wins.
+func (rules ignoreRules) excluded(rel string, isDir bool) bool {
+	excluded := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		var match bool
+		if rule.segments != nil {
+			match = matchSegments(rule.segments, strings.Split(rel, "/"))
+		} else {
+			match, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if match {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,158 @@
+package manusai
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]string{
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"/build",
+		"node_modules/",
+		"docs/**/*.tmp",
+	})
+
+	assert.True(t, rules.excluded("a/b/debug.log", false))
+	assert.False(t, rules.excluded("a/keep.log", false))
+	assert.True(t, rules.excluded("build", true))
+	assert.False(t, rules.excluded("src/build", true))
+	assert.True(t, rules.excluded("web/node_modules", true))
+	assert.False(t, rules.excluded("node_modules", false))
+	assert.True(t, rules.excluded("docs/x/y/z.tmp", false))
+	assert.False(t, rules.excluded("src/main.go", false))
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestNewArchiveAttachmentZip(t *testing.T) {
+	api := newFakeFileAPI(t)
+	client, err := NewClient("test-key", WithBaseURL(api.server.URL))
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		".gitignore":           {Data: []byte("*.log\n/dist/\n")},
+		"main.go":              {Data: []byte("package main\n")},
+		"pkg/util.go":          {Data: []byte("package pkg\n")},
+		"pkg/debug.log":        {Data: []byte("noise")},
+		"dist/app":             {Data: []byte("binary")},
+		"testdata/big.fixture": {Data: []byte("fixture")},
+	}
+
+	attachment, err := NewArchiveAttachment(context.Background(), client, fsys, &ArchiveOptions{
+		Exclude:    []string{"testdata/"},
+		IgnoreFile: ".gitignore",
+		Name:       "src.zip",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "file_1", attachment["file_id"])
+
+	files := readZip(t, []byte(api.uploaded["file_1"]))
+	assert.ElementsMatch(t, []string{".gitignore", "main.go", "pkg/util.go", "manifest.json"}, keys(files))
+	assert.Equal(t, "package pkg\n", files["pkg/util.go"])
+
+	var manifest ArchiveManifest
+	require.NoError(t, json.Unmarshal([]byte(files["manifest.json"]), &manifest))
+	require.Len(t, manifest.Files, 3)
+	sum := sha256.Sum256([]byte("package main\n"))
+	assert.Equal(t, ArchiveManifestEntry{Path: "main.go", SizeBytes: 13, SHA256: hex.EncodeToString(sum[:])}, manifest.Files[1])
+}
+
+func TestNewArchiveAttachmentTarGz(t *testing.T) {
+	api := newFakeFileAPI(t)
+	client, err := NewClient("test-key", WithBaseURL(api.server.URL))
+	require.NoError(t, err)
+
+	dir := writeTree(t, map[string]string{
+		"src/a.txt":   "alpha",
+		"src/b/c.txt": "charlie",
+		"notes.md":    "notes",
+	})
+
+	_, err = NewArchiveAttachment(context.Background(), client,
+		[]string{dir + "/src", dir + "/notes.md"},
+		&ArchiveOptions{Format: ArchiveTarGz, NoManifest: true})
+	require.NoError(t, err)
+
+	gz, err := gzip.NewReader(bytes.NewReader([]byte(api.uploaded["file_1"])))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(content)
+	}
+	assert.Equal(t, map[string]string{"notes.md": "notes", "src/a.txt": "alpha", "src/b/c.txt": "charlie"}, files)
+}
+
+func TestNewArchiveAttachmentErrors(t *testing.T) {
+	api := newFakeFileAPI(t)
+	client, err := NewClient("test-key", WithBaseURL(api.server.URL))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = NewArchiveAttachment(ctx, client, 42, nil)
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = NewArchiveAttachment(ctx, client, fstest.MapFS{"a.log": {Data: []byte("x")}}, &ArchiveOptions{Exclude: []string{"*.log"}})
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = NewArchiveAttachment(ctx, client, fstest.MapFS{"manifest.json": {Data: []byte("{}")}}, nil)
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = NewArchiveAttachment(ctx, client, fstest.MapFS{"a.txt": {Data: []byte("x")}}, &ArchiveOptions{Format: "rar"})
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, 0, api.created)
+}
+
+func TestNewArchiveAttachmentDeletesFileOnFailure(t *testing.T) {
+	api := newFakeFileAPI(t)
+	api.failUploads = true
+	client, err := NewClient("test-key", WithBaseURL(api.server.URL))
+	require.NoError(t, err)
+
+	_, err = NewArchiveAttachment(context.Background(), client, fstest.MapFS{"a.txt": {Data: []byte("x")}}, nil)
+	require.Error(t, err)
+	assert.Equal(t, 1, api.created)
+	assert.Equal(t, map[string]bool{"file_1": true}, api.deleted)
+}
+
+func keys(m map[string]string) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -15,6 +15,28 @@ func NewAttachmentFromFileID(fileID string) map[string]interface{} {
 	}
 }
 
+// attachmentFileIDs returns the IDs of file_id attachments.
+func attachmentFileIDs(attachments []interface{}) []string {
+	var ids []string
+	for _, att := range attachments {
+		var fileID string
+		switch v := att.(type) {
+		case map[string]interface{}:
+			fileID, _ = v["file_id"].(string)
+		case TaskAttachment:
+			fileID = v.FileID
+		case *TaskAttachment:
+			if v != nil {
+				fileID = v.FileID
+			}
+		}
+		if fileID != "" {
+			ids = append(ids, fileID)
+		}
+	}
+	return ids
+}
+
 func NewAttachmentFromURL(url string) map[string]interface{} {
 	return map[string]interface{}{
 		"type": "url",
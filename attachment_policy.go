@@ -0,0 +1,227 @@
+package manusai
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	DefaultMaxInlineAttachmentSize = DefaultInlineAttachmentLimit
+	DefaultMaxTotalAttachmentSize  = 50 << 20
+)
+
+// AttachmentPolicy is checked by CreateTask before anything is sent. Zero
+// fields impose no limit, so AttachmentPolicy{} allows everything.
+type AttachmentPolicy struct {
+	// MaxInlineSize limits the decoded size of each inline data attachment.
+	MaxInlineSize int64
+	// MaxTotalSize limits the combined decoded size of inline data
+	// attachments in one task. Data attachments of unknown size are not
+	// counted.
+	MaxTotalSize int64
+	// AllowedMIMETypes are patterns such as "image/*" that the MIME type of
+	// every data attachment, inline or uploaded, must match.
+	AllowedMIMETypes []string
+	// BlockedMIMETypes are patterns that no data attachment may match, even
+	// if AllowedMIMETypes does.
+	BlockedMIMETypes []string
+	// AllowedURLSchemes and AllowedURLHosts restrict url attachments. A host
+	// pattern may start with "*." to allow subdomains.
+	AllowedURLSchemes []string
+	AllowedURLHosts   []string
+}
+
+// DefaultAttachmentPolicy limits inline sizes, blocks executables and
+// installers, and only accepts http and https URLs. Other content, including
+// application/octet-stream, is allowed.
+func DefaultAttachmentPolicy() AttachmentPolicy {
+	return AttachmentPolicy{
+		MaxInlineSize: DefaultMaxInlineAttachmentSize,
+		MaxTotalSize:  DefaultMaxTotalAttachmentSize,
+		BlockedMIMETypes: []string{
+			"application/x-msdownload",
+			"application/x-msdos-program",
+			"application/x-dosexec",
+			"application/x-ms-installer",
+			"application/x-msi",
+			"application/vnd.microsoft.portable-executable",
+			"application/x-executable",
+			"application/x-elf",
+			"application/x-sharedlib",
+			"application/x-mach-binary",
+			"application/x-apple-diskimage",
+			"application/vnd.android.package-archive",
+		},
+		AllowedURLSchemes: []string{"https", "http"},
+	}
+}
+
+// StrictAttachmentPolicy is DefaultAttachmentPolicy restricted to documents,
+// images, audio, video, text and common archives. It rejects
+// application/octet-stream, which NewAttachmentFromFilePath uses for files
+// without a known extension.
+func StrictAttachmentPolicy() AttachmentPolicy {
+	policy := DefaultAttachmentPolicy()
+	policy.AllowedMIMETypes = []string{
+		"text/*",
+		"image/*",
+		"audio/*",
+		"video/*",
+		"application/pdf",
+		"application/json",
+		"application/xml",
+		"application/rtf",
+		"application/zip",
+		"application/gzip",
+		"application/x-tar",
+		"application/msword",
+		"application/vnd.ms-excel",
+		"application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.*",
+		"application/vnd.oasis.opendocument.*",
+	}
+	return policy
+}
+
+func WithAttachmentPolicy(policy AttachmentPolicy) ClientOption {
+	return func(c *Client) {
+		c.attachmentPolicy = policy
+	}
+}
+
+// Check validates attachments against the policy, naming the index of the
+// first offending attachment.
+func (p AttachmentPolicy) Check(attachments []interface{}) error {
+	return p.check(attachments, -1)
+}
+
+// check skips the inline size limits for data attachments larger than
+// inlineLimit, which CreateTask uploads instead; a negative inlineLimit
+// treats every attachment as inline.
+func (p AttachmentPolicy) check(attachments []interface{}, inlineLimit int64) error {
+	var total int64
+	for i, att := range attachments {
+		var err error
+		switch a := att.(type) {
+		case *DataAttachment:
+			uploaded := inlineLimit >= 0 && a.Size > inlineLimit
+			err = p.checkMIMEType(a.MimeType)
+			if err == nil && a.Size >= 0 && !uploaded {
+				err = p.checkInlineSize(a.Size, &total)
+			}
+		case map[string]interface{}:
+			typ, _ := a["type"].(string)
+			mimeType, _ := a["mime_type"].(string)
+			data, _ := a["data"].(string)
+			rawURL, _ := a["url"].(string)
+			err = p.checkEncoded(typ, mimeType, data, rawURL, &total)
+		case TaskAttachment:
+			err = p.checkEncoded(a.Type, a.MimeType, a.Data, a.URL, &total)
+		case *TaskAttachment:
+			if a != nil {
+				err = p.checkEncoded(a.Type, a.MimeType, a.Data, a.URL, &total)
+			}
+		}
+		if err != nil {
+			return &ValidationError{Message: fmt.Sprintf("Attachment %d: %v", i, err)}
+		}
+	}
+	return nil
+}
+
+// checkEncoded checks a data or url attachment given as a map or a
+// TaskAttachment.
+func (p AttachmentPolicy) checkEncoded(typ, mimeType, data, rawURL string, total *int64) error {
+	switch typ {
+	case "data":
+		if err := p.checkMIMEType(mimeType); err != nil {
+			return err
+		}
+		return p.checkInlineSize(base64DecodedLen(data), total)
+	case "url":
+		return p.checkURL(rawURL)
+	}
+	return nil
+}
+
+func (p AttachmentPolicy) checkInlineSize(size int64, total *int64) error {
+	if p.MaxInlineSize > 0 && size > p.MaxInlineSize {
+		return fmt.Errorf("size %d bytes exceeds the inline limit of %d bytes", size, p.MaxInlineSize)
+	}
+	*total += size
+	if p.MaxTotalSize > 0 && *total > p.MaxTotalSize {
+		return fmt.Errorf("attachments total %d bytes, over the limit of %d bytes", *total, p.MaxTotalSize)
+	}
+	return nil
+}
+
+func (p AttachmentPolicy) checkMIMEType(mimeType string) error {
+	if len(p.AllowedMIMETypes) == 0 && len(p.BlockedMIMETypes) == 0 {
+		return nil
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return fmt.Errorf("invalid MIME type %q", mimeType)
+	}
+	if matchMIMEType(p.BlockedMIMETypes, mediaType) ||
+		(len(p.AllowedMIMETypes) > 0 && !matchMIMEType(p.AllowedMIMETypes, mediaType)) {
+		return fmt.Errorf("MIME type %q is not allowed", mediaType)
+	}
+	return nil
+}
+
+func matchMIMEType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, mediaType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p AttachmentPolicy) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	if len(p.AllowedURLSchemes) > 0 && !containsFold(p.AllowedURLSchemes, u.Scheme) {
+		return fmt.Errorf("URL scheme %q is not allowed", u.Scheme)
+	}
+
+	if len(p.AllowedURLHosts) > 0 {
+		host := strings.ToLower(u.Hostname())
+		for _, pattern := range p.AllowedURLHosts {
+			pattern = strings.ToLower(pattern)
+			if host == pattern || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+				return nil
+			}
+		}
+		return fmt.Errorf("URL host %q is not allowed", host)
+	}
+	return nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func base64DecodedLen(data string) int64 {
+	n := int64(len(data)) / 4 * 3
+	n -= int64(len(data) - len(strings.TrimRight(data, "=")))
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package manusai
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentPolicyCheck(t *testing.T) {
+	policy := AttachmentPolicy{
+		MaxInlineSize:     10,
+		MaxTotalSize:      15,
+		AllowedMIMETypes:  []string{"text/*", "application/pdf"},
+		AllowedURLSchemes: []string{"https"},
+		AllowedURLHosts:   []string{"example.com", "*.cdn.example.com"},
+	}
+	data := func(s, mimeType string) map[string]interface{} {
+		return NewAttachmentFromBase64(base64.StdEncoding.EncodeToString([]byte(s)), mimeType)
+	}
+
+	tests := []struct {
+		name        string
+		attachments []interface{}
+		err         string
+	}{
+		{"allowed", []interface{}{
+			NewAttachmentFromFileID("file_1"),
+			data("hello", "text/plain; charset=utf-8"),
+			NewAttachmentFromURL("https://example.com/a.pdf"),
+			NewAttachmentFromURL("https://img.cdn.example.com/b.png"),
+		}, ""},
+		{"inline size", []interface{}{data("ok", "text/plain"), data("eleven char", "text/plain")}, "Attachment 1: size 11 bytes"},
+		{"total size", []interface{}{data("0123456789", "text/plain"), data("012345", "text/plain")}, "Attachment 1: attachments total 16 bytes"},
+		{"mime type", []interface{}{data("MZ", "application/x-msdownload")}, `Attachment 0: MIME type "application/x-msdownload"`},
+		{"missing mime type", []interface{}{data("MZ", "")}, `"application/octet-stream" is not allowed`},
+		{"scheme", []interface{}{NewAttachmentFromURL("http://example.com/a.pdf")}, `URL scheme "http"`},
+		{"host", []interface{}{NewAttachmentFromURL("https://evil.com/a.pdf")}, `URL host "evil.com"`},
+		{"streamed", []interface{}{NewDataAttachment("a.bin", "", 4, openString("data"))}, "Attachment 0: MIME type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.attachments)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.IsType(t, &ValidationError{}, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	assert.NoError(t, AttachmentPolicy{}.Check(tests[3].attachments))
+}
+
+func TestDefaultAttachmentPolicy(t *testing.T) {
+	policy := DefaultAttachmentPolicy()
+	assert.NoError(t, policy.Check([]interface{}{
+		NewAttachmentFromBase64("", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"),
+		NewAttachmentFromBase64("", "image/png"),
+		NewAttachmentFromBase64("", "application/octet-stream"),
+		NewAttachmentFromBase64("", "application/x-yaml"),
+		NewAttachmentFromURL("https://example.com/a.pdf"),
+	}))
+	assert.Error(t, policy.Check([]interface{}{NewAttachmentFromBase64("", "application/x-msdownload")}))
+	assert.Error(t, policy.Check([]interface{}{NewAttachmentFromURL("file:///etc/passwd")}))
+
+	strict := StrictAttachmentPolicy()
+	assert.NoError(t, strict.Check([]interface{}{NewAttachmentFromBase64("", "image/png")}))
+	assert.Error(t, strict.Check([]interface{}{NewAttachmentFromBase64("", "application/octet-stream")}))
+	assert.Error(t, strict.Check([]interface{}{NewAttachmentFromBase64("", "application/x-msdownload")}))
+}
+
+func TestAttachmentPolicyTaskAttachment(t *testing.T) {
+	policy := AttachmentPolicy{
+		MaxInlineSize:     4,
+		BlockedMIMETypes:  []string{"application/x-msdownload"},
+		AllowedURLSchemes: []string{"https"},
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte("too long"))
+
+	tests := []struct {
+		attachment interface{}
+		err        string
+	}{
+		{TaskAttachment{Type: "data", Data: encoded, MimeType: "text/plain"}, "size 8 bytes"},
+		{&TaskAttachment{Type: "data", Data: "TVo=", MimeType: "application/x-msdownload"}, "MIME type"},
+		{TaskAttachment{Type: "url", URL: "ftp://example.com/a.pdf"}, `URL scheme "ftp"`},
+		{&TaskAttachment{Type: "url", URL: "http://example.com/a.pdf"}, `URL scheme "http"`},
+		{TaskAttachment{Type: "file_id", FileID: "file_1"}, ""},
+	}
+	for _, tt := range tests {
+		err := policy.Check([]interface{}{tt.attachment})
+		if tt.err == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tt.err)
+	}
+}
+
+func TestCreateTaskAttachmentPolicy(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"task_id":"task_1"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithInlineAttachmentLimit(8),
+		WithAttachmentPolicy(AttachmentPolicy{MaxInlineSize: 4, AllowedMIMETypes: []string{"text/*"}}))
+	require.NoError(t, err)
+
+	_, err = client.CreateTask("Read", &TaskOptions{Attachments: []interface{}{
+		NewDataAttachment("a.txt", "text/plain", 6, openString("sixsix")),
+	}})
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Contains(t, err.Error(), "Attachment 0: size 6 bytes")
+	assert.Equal(t, 0, requests)
+
+	// Attachments above the inline limit are uploaded, so only their MIME
+	// type is checked.
+	err = client.attachmentPolicy.check([]interface{}{NewDataAttachment("big.txt", "text/plain", 100, openString(""))}, client.inlineLimit)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,333 @@
+package manusai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// DefaultInlineAttachmentLimit is the largest DataAttachment sent inline;
+// larger ones are uploaded through the Files API.
+const DefaultInlineAttachmentLimit = 10 << 20
+
+// DataAttachment is an inline "data" attachment whose content is read and
+// base64-encoded while the request body is being sent, so it is never held
+// in memory. Open is called again for every request attempt and must return
+// the same bytes each time.
+type DataAttachment struct {
+	Filename string
+	MimeType string
+	// Size is the raw content length, or -1 if unknown. Attachments of
+	// unknown size are always sent inline.
+	Size int64
+	Open func() (io.ReadCloser, error)
+}
+
+func NewDataAttachment(filename, mimeType string, size int64, open func() (io.ReadCloser, error)) *DataAttachment {
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return &DataAttachment{Filename: filename, MimeType: mimeType, Size: size, Open: open}
+}
+
+// NewDataAttachmentFromFilePath is the streaming counterpart of
+// NewAttachmentFromFilePath.
+func NewDataAttachmentFromFilePath(filePath string) (*DataAttachment, error) {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	open := func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}
+	return NewDataAttachment(filepath.Base(filePath), mime.TypeByExtension(filepath.Ext(filePath)), info.Size(), open), nil
+}
+
+// MarshalJSON encodes the attachment in memory. Requests made by the client
+// stream it instead.
+func (d *DataAttachment) MarshalJSON() ([]byte, error) {
+	src, err := d.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(&base64Reader{src: src})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(NewAttachmentFromBase64(string(data), d.MimeType))
+}
+
+func (d *DataAttachment) prefix() []byte {
+	mimeType, _ := json.Marshal(d.MimeType)
+	return []byte(`{"type":"data","mime_type":` + string(mimeType) + `,"data":"`)
+}
+
+var dataAttachmentSuffix = []byte(`"}`)
+
+func WithInlineAttachmentLimit(limit int64) ClientOption {
+	return func(c *Client) {
+		c.inlineLimit = limit
+	}
+}
+
+// uploadLargeAttachments replaces data attachments above the inline limit
+// with file_id attachments uploaded through the Files API, and returns the
+// IDs of the files it created. A negative limit keeps everything inline.
+func (c *Client) uploadLargeAttachments(ctx context.Context, attachments []interface{}) ([]interface{}, []string, error) {
+	if c.inlineLimit < 0 {
+		return attachments, nil, nil
+	}
+
+	var resolved []interface{}
+	var uploaded []string
+	for i, att := range attachments {
+		d, ok := att.(*DataAttachment)
+		if !ok || d.Size < 0 || d.Size <= c.inlineLimit {
+			continue
+		}
+		if resolved == nil {
+			resolved = append([]interface{}(nil), attachments...)
+		}
+
+		fileID, err := c.uploadDataAttachment(ctx, d)
+		if err != nil {
+			c.discardFiles(uploaded)
+			return nil, nil, err
+		}
+		uploaded = append(uploaded, fileID)
+		resolved[i] = NewAttachmentFromFileID(fileID)
+	}
+
+	if resolved == nil {
+		return attachments, nil, nil
+	}
+	return resolved, uploaded, nil
+}
+
+func (c *Client) uploadDataAttachment(ctx context.Context, d *DataAttachment) (string, error) {
+	filename := d.Filename
+	if filename == "" {
+		filename = "attachment"
+	}
+
+	file, err := c.createFile(ctx, filename, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := c.UploadFileReader(ctx, file.UploadURL, d.Open, d.Size, &UploadOptions{ContentType: d.MimeType}); err != nil {
+		c.discardFiles([]string{file.ID})
+		return "", err
+	}
+	if _, err := c.WaitForFile(ctx, file.ID); err != nil {
+		c.discardFiles([]string{file.ID})
+		return "", err
+	}
+	return file.ID, nil
+}
+
+// discardFiles deletes files uploaded for a task that was not created. It
+// does not use the request context, which may already be done, and ignores
+// errors; FileJanitor collects anything left behind.
+func (c *Client) discardFiles(fileIDs []string) {
+	for _, fileID := range fileIDs {
+		_, _ = c.DeleteFile(fileID)
+	}
+}
+
+// requestBody is a JSON request body that can be produced more than once.
+// Streamed attachments sit between the buffered JSON parts.
+type requestBody struct {
+	parts   [][]byte
+	streams []*DataAttachment
+	// length is the encoded size, or -1 when a stream size is unknown.
+	length int64
+}
+
+// encodeRequestBody marshals body, leaving any DataAttachment in a payload's
+// "attachments" out of the buffer so it can be streamed.
+func encodeRequestBody(body interface{}) (*requestBody, error) {
+	var streams []*DataAttachment
+	marker := []byte(`"manus-stream-` + randomID() + `"`)
+
+	if payload, ok := body.(map[string]interface{}); ok {
+		if attachments, ok := payload["attachments"].([]interface{}); ok {
+			var replaced []interface{}
+			for i, att := range attachments {
+				d, ok := att.(*DataAttachment)
+				if !ok {
+					continue
+				}
+				if replaced == nil {
+					replaced = append([]interface{}(nil), attachments...)
+				}
+				replaced[i] = json.RawMessage(marker)
+				streams = append(streams, d)
+			}
+			if replaced != nil {
+				copied := make(map[string]interface{}, len(payload))
+				for k, v := range payload {
+					copied[k] = v
+				}
+				copied["attachments"] = replaced
+				body = copied
+			}
+		}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &requestBody{parts: [][]byte{data}, streams: streams, length: int64(len(data))}
+	if len(streams) == 0 {
+		return b, nil
+	}
+
+	b.parts = bytes.Split(data, marker)
+	if len(b.parts) != len(streams)+1 {
+		return nil, fmt.Errorf("unexpected attachment layout in request body")
+	}
+	b.length = int64(len(data) - len(streams)*len(marker))
+	for _, d := range streams {
+		if d.Size < 0 {
+			b.length = -1
+			break
+		}
+		b.length += int64(len(d.prefix()) + base64.StdEncoding.EncodedLen(int(d.Size)) + len(dataAttachmentSuffix))
+	}
+	return b, nil
+}
+
+func (b *requestBody) reader() io.ReadCloser {
+	var parts []func() (io.ReadCloser, error)
+	for i, part := range b.parts {
+		parts = append(parts, bytesPart(part))
+		if i < len(b.streams) {
+			d := b.streams[i]
+			parts = append(parts, bytesPart(d.prefix()), func() (io.ReadCloser, error) {
+				src, err := d.Open()
+				if err != nil {
+					return nil, &attachmentReadError{err: err}
+				}
+				return struct {
+					io.Reader
+					io.Closer
+				}{&base64Reader{src: attachmentSource{src}}, src}, nil
+			}, bytesPart(dataAttachmentSuffix))
+		}
+	}
+	return &multiPartReader{parts: parts}
+}
+
+// attachmentReadError is a failure to read a DataAttachment while the
+// request body is streamed. It says nothing about the server.
+type attachmentReadError struct {
+	err error
+}
+
+func (e *attachmentReadError) Error() string { return e.err.Error() }
+
+func (e *attachmentReadError) Unwrap() error { return e.err }
+
+// attachmentSource marks read errors from a DataAttachment's reader.
+type attachmentSource struct {
+	io.Reader
+}
+
+func (s attachmentSource) Read(p []byte) (int, error) {
+	n, err := s.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = &attachmentReadError{err: err}
+	}
+	return n, err
+}
+
+func bytesPart(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// multiPartReader reads each part in turn, opening it only when reached.
+type multiPartReader struct {
+	parts []func() (io.ReadCloser, error)
+	cur   io.ReadCloser
+}
+
+func (r *multiPartReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.parts[0]()
+			if err != nil {
+				return 0, err
+			}
+			r.parts, r.cur = r.parts[1:], rc
+		}
+
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *multiPartReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	err := r.cur.Close()
+	r.cur = nil
+	return err
+}
+
+// base64Reader encodes src in chunks that are a multiple of three bytes, so
+// padding only ever appears at the end.
+type base64Reader struct {
+	src io.Reader
+	in  [3 * 1024]byte
+	buf [4 * 1024]byte
+	out []byte
+	eof bool
+}
+
+func (r *base64Reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.in[:])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			r.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+		base64.StdEncoding.Encode(r.buf[:], r.in[:n])
+		r.out = r.buf[:base64.StdEncoding.EncodedLen(n)]
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
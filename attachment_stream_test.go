@@ -0,0 +1,205 @@
+package manusai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBase64Reader(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 3071, 3072, 3073, 10000} {
+		data := bytes.Repeat([]byte("abcdefg"), size/7+1)[:size]
+		r := iotest.OneByteReader(&base64Reader{src: iotest.HalfReader(bytes.NewReader(data))})
+		encoded, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(data), string(encoded), "size %d", size)
+	}
+}
+
+func openString(s string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(s)), nil
+	}
+}
+
+func TestEncodeRequestBody(t *testing.T) {
+	payload := map[string]interface{}{
+		"prompt": "Summarize",
+		"attachments": []interface{}{
+			NewAttachmentFromURL("https://example.com/a.pdf"),
+			NewDataAttachment("notes.txt", "text/plain", 11, openString("hello world")),
+			NewDataAttachment("more.txt", "text/plain", -1, openString("again")),
+		},
+	}
+
+	body, err := encodeRequestBody(payload)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), body.length)
+
+	for i := 0; i < 2; i++ {
+		data, err := io.ReadAll(body.reader())
+		require.NoError(t, err)
+
+		var decoded struct {
+			Prompt      string                   `json:"prompt"`
+			Attachments []map[string]interface{} `json:"attachments"`
+		}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, "Summarize", decoded.Prompt)
+		require.Len(t, decoded.Attachments, 3)
+		assert.Equal(t, "url", decoded.Attachments[0]["type"])
+		assert.Equal(t, "data", decoded.Attachments[1]["type"])
+		assert.Equal(t, "text/plain", decoded.Attachments[1]["mime_type"])
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("hello world")), decoded.Attachments[1]["data"])
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("again")), decoded.Attachments[2]["data"])
+	}
+
+	// The caller's payload is left untouched.
+	assert.IsType(t, &DataAttachment{}, payload["attachments"].([]interface{})[1])
+
+	payload["attachments"] = payload["attachments"].([]interface{})[:2]
+	body, err = encodeRequestBody(payload)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body.reader())
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), body.length)
+}
+
+func TestDataAttachmentMarshalJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	require.NoError(t, os.WriteFile(path, []byte("a,b\n1,2\n"), 0644))
+
+	att, err := NewDataAttachmentFromFilePath(path)
+	require.NoError(t, err)
+	assert.Equal(t, "report.csv", att.Filename)
+	assert.Equal(t, int64(8), att.Size)
+
+	streamed, err := json.Marshal(att)
+	require.NoError(t, err)
+	inline, err := NewAttachmentFromFilePath(path)
+	require.NoError(t, err)
+	buffered, err := json.Marshal(inline)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(buffered), string(streamed))
+
+	_, err = NewDataAttachmentFromFilePath(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
+
+func TestCreateTaskUploadsLargeAttachments(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	var taskBody map[string]interface{}
+	var uploaded string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/files":
+			json.NewEncoder(w).Encode(FileResponse{ID: "file_1", UploadURL: server.URL + "/upload/file_1"})
+		case r.Method == "PUT":
+			data, _ := io.ReadAll(r.Body)
+			uploaded = string(data)
+		case r.Method == "GET" && r.URL.Path == "/v1/files/file_1":
+			json.NewEncoder(w).Encode(FileDetail{ID: "file_1", Status: string(FileStatusReady)})
+		case r.Method == "POST" && r.URL.Path == "/v1/tasks":
+			assert.Greater(t, r.ContentLength, int64(0))
+			json.NewDecoder(r.Body).Decode(&taskBody)
+			w.Write([]byte(`{"task_id":"task_1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := NewMemoryTaskStore()
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithInlineAttachmentLimit(8), WithTaskStore(store))
+	require.NoError(t, err)
+
+	_, err = client.CreateTask("Compare", &TaskOptions{
+		Attachments: []interface{}{
+			NewDataAttachment("small.txt", "text/plain", 5, openString("small")),
+			NewDataAttachment("large.txt", "text/plain", 16, openString("sixteen bytes!!!")),
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"POST /v1/files", "PUT /upload/file_1", "GET /v1/files/file_1", "POST /v1/tasks"}, requests)
+	assert.Equal(t, "sixteen bytes!!!", uploaded)
+
+	attachments := taskBody["attachments"].([]interface{})
+	require.Len(t, attachments, 2)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("small")), attachments[0].(map[string]interface{})["data"])
+	assert.Equal(t, "file_1", attachments[1].(map[string]interface{})["file_id"])
+
+	record, _ := store.Get("task_1")
+	require.NotNil(t, record)
+	assert.Equal(t, []string{"file_1"}, record.FileIDs)
+}
+
+func TestCreateTaskDeletesUploadedAttachmentsOnFailure(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	putStatus, taskStatus := http.StatusOK, http.StatusBadRequest
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/files":
+			json.NewEncoder(w).Encode(FileResponse{ID: "file_1", UploadURL: server.URL + "/upload/file_1"})
+		case r.Method == "PUT":
+			w.WriteHeader(putStatus)
+		case r.Method == "GET" && r.URL.Path == "/v1/files/file_1":
+			json.NewEncoder(w).Encode(FileDetail{ID: "file_1", Status: string(FileStatusReady)})
+		case r.Method == "DELETE":
+			w.Write([]byte(`{"deleted":true}`))
+		case r.Method == "POST" && r.URL.Path == "/v1/tasks":
+			w.WriteHeader(taskStatus)
+			w.Write([]byte(`{"message":"bad request"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithInlineAttachmentLimit(8))
+	require.NoError(t, err)
+	options := &TaskOptions{
+		Attachments: []interface{}{NewDataAttachment("large.txt", "text/plain", 16, openString("sixteen bytes!!!"))},
+	}
+
+	_, err = client.CreateTask("Compare", options)
+	require.Error(t, err)
+	assert.Equal(t, []string{"POST /v1/files", "PUT /upload/file_1", "GET /v1/files/file_1", "POST /v1/tasks", "DELETE /v1/files/file_1"}, requests)
+
+	requests = nil
+	putStatus = http.StatusForbidden
+	_, err = client.CreateTask("Compare", options)
+	require.Error(t, err)
+	assert.Equal(t, []string{"POST /v1/files", "PUT /upload/file_1", "DELETE /v1/files/file_1"}, requests)
+
+	// A task that may have been created keeps its files.
+	requests = nil
+	putStatus, taskStatus = http.StatusOK, http.StatusBadGateway
+	_, err = client.CreateTask("Compare", options)
+	require.Error(t, err)
+	assert.NotContains(t, requests, "DELETE /v1/files/file_1")
+}
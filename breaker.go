@@ -0,0 +1,289 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerFailureRatio = 0.5
+	DefaultBreakerMinRequests  = 10
+	DefaultBreakerWindow       = time.Minute
+	DefaultBreakerOpenTimeout  = 30 * time.Second
+	DefaultBreakerProbes       = 1
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type CircuitBreakerConfig struct {
+	// FailureRatio trips the circuit once this share of requests in the
+	// current window failed, provided at least MinRequests were made.
+	FailureRatio float64
+	MinRequests  int
+	Window       time.Duration
+	// OpenTimeout is how long the circuit stays open before letting probes
+	// through.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is how many concurrent probes are allowed, and how many
+	// must succeed to close the circuit again.
+	HalfOpenProbes int
+	OnStateChange  func(endpoint string, from, to CircuitState)
+	// IsFailure decides which errors count against the endpoint. By default
+	// network errors, 429 and 5xx responses do; other API errors do not.
+	IsFailure func(err error) bool
+	Now       func() time.Time
+}
+
+// WithCircuitBreaker makes requests fail fast with CircuitOpenError while an
+// endpoint is unhealthy. Endpoints are tracked by method and path, with IDs
+// collapsed, e.g. "GET /v1/tasks/{id}".
+func WithCircuitBreaker(config CircuitBreakerConfig) ClientOption {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(config)
+	}
+}
+
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+
+	mu        sync.Mutex
+	endpoints map[string]*circuit
+}
+
+type circuit struct {
+	state CircuitState
+	// generation changes on every transition.
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	if config.FailureRatio <= 0 {
+		config.FailureRatio = DefaultBreakerFailureRatio
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = DefaultBreakerMinRequests
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultBreakerWindow
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = DefaultBreakerProbes
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isServerFailure
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &circuitBreaker{config: config, endpoints: make(map[string]*circuit)}
+}
+
+// CircuitState reports the breaker state for an endpoint such as
+// "GET /v1/tasks/{id}". It is CircuitClosed when no breaker is configured.
+func (c *Client) CircuitState(endpoint string) CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	if cb, ok := c.breaker.endpoints[endpoint]; ok {
+		return cb.state
+	}
+	return CircuitClosed
+}
+
+// allow admits a request and returns the generation of the circuit state it
+// was admitted under, to be passed back to record.
+func (b *circuitBreaker) allow(endpoint string) (uint64, error) {
+	b.mu.Lock()
+	gen, change, err := b.allowLocked(endpoint)
+	b.mu.Unlock()
+
+	b.notify(change)
+	return gen, err
+}
+
+func (b *circuitBreaker) allowLocked(endpoint string) (uint64, *stateChange, error) {
+	cb := b.circuit(endpoint)
+	now := b.config.Now()
+
+	switch cb.state {
+	case CircuitOpen:
+		retryAfter := cb.openedAt.Add(b.config.OpenTimeout).Sub(now)
+		if retryAfter > 0 {
+			return 0, nil, &CircuitOpenError{Endpoint: endpoint, RetryAfter: retryAfter}
+		}
+		change := b.transition(endpoint, cb, CircuitHalfOpen)
+		cb.probes = 1
+		return cb.generation, change, nil
+	case CircuitHalfOpen:
+		if cb.probes >= b.config.HalfOpenProbes {
+			return 0, nil, &CircuitOpenError{Endpoint: endpoint}
+		}
+		cb.probes++
+		return cb.generation, nil, nil
+	default:
+		return cb.generation, nil, nil
+	}
+}
+
+// record counts the outcome of a request admitted under generation gen.
+// Requests that finish after the circuit has changed state are ignored, so a
+// slow request from before the circuit opened cannot use up a probe.
+func (b *circuitBreaker) record(ctx context.Context, endpoint string, gen uint64, err error) {
+	// A caller giving up is not a sign that the endpoint is healthy or not.
+	cancelled := ctx.Err() != nil
+	failed := err != nil && !cancelled && b.config.IsFailure(err)
+
+	b.mu.Lock()
+	change := b.recordLocked(endpoint, gen, cancelled, failed)
+	b.mu.Unlock()
+
+	b.notify(change)
+}
+
+func (b *circuitBreaker) recordLocked(endpoint string, gen uint64, cancelled, failed bool) *stateChange {
+	cb := b.circuit(endpoint)
+	if cb.generation != gen {
+		return nil
+	}
+	now := b.config.Now()
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.probes--
+		if cancelled {
+			return nil
+		}
+		if failed {
+			cb.openedAt = now
+			return b.transition(endpoint, cb, CircuitOpen)
+		}
+		cb.successes++
+		if cb.successes >= b.config.HalfOpenProbes {
+			return b.transition(endpoint, cb, CircuitClosed)
+		}
+	case CircuitClosed:
+		if now.Sub(cb.windowStart) > b.config.Window {
+			cb.windowStart = now
+			cb.requests = 0
+			cb.failures = 0
+		}
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+		if cb.requests >= b.config.MinRequests &&
+			float64(cb.failures)/float64(cb.requests) >= b.config.FailureRatio {
+			cb.openedAt = now
+			return b.transition(endpoint, cb, CircuitOpen)
+		}
+	}
+	return nil
+}
+
+func (b *circuitBreaker) circuit(endpoint string) *circuit {
+	cb, ok := b.endpoints[endpoint]
+	if !ok {
+		cb = &circuit{windowStart: b.config.Now()}
+		b.endpoints[endpoint] = cb
+	}
+	return cb
+}
+
+type stateChange struct {
+	endpoint string
+	from, to CircuitState
+}
+
+// transition moves cb to a new state. The returned change is passed to
+// notify once b.mu is released, so OnStateChange may use the client.
+func (b *circuitBreaker) transition(endpoint string, cb *circuit, to CircuitState) *stateChange {
+	from := cb.state
+	cb.state = to
+	cb.generation++
+	cb.probes = 0
+	cb.successes = 0
+	if to == CircuitClosed {
+		cb.windowStart = b.config.Now()
+		cb.requests = 0
+		cb.failures = 0
+	}
+	if from == to {
+		return nil
+	}
+	return &stateChange{endpoint: endpoint, from: from, to: to}
+}
+
+func (b *circuitBreaker) notify(change *stateChange) {
+	if change != nil && b.config.OnStateChange != nil {
+		b.config.OnStateChange(change.endpoint, change.from, change.to)
+	}
+}
+
+// breakerEndpoint collapses resource IDs so that all requests for, say, a
+// single task share one circuit: "GET /v1/tasks/task_1" -> "GET /v1/tasks/{id}".
+func breakerEndpoint(method, endpoint string) string {
+	segments := strings.Split(strings.Trim(endpoint, "/"), "/")
+	for i := 2; i < len(segments); i++ {
+		segments[i] = "{id}"
+	}
+	return method + " /" + strings.Join(segments, "/")
+}
+
+// isServerFailure reports whether err says something about the endpoint's
+// health: the request could not be delivered or answered, or the server
+// returned 429 or 5xx. Encoding, decoding and attachment read errors do not.
+func isServerFailure(err error) bool {
+	var apiErr *ManusAIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == 0 {
+		var transportErr *transportError
+		return errors.As(err, &transportErr)
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
+
+// transportError marks a request that failed in transit: the connection
+// could not be made or broke before the response was read.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+
+func (e *transportError) Unwrap() error { return e.err }
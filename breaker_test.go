@@ -0,0 +1,185 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerEndpoint(t *testing.T) {
+	assert.Equal(t, "GET /v1/tasks", breakerEndpoint("GET", "/v1/tasks"))
+	assert.Equal(t, "GET /v1/tasks/{id}", breakerEndpoint("GET", "/v1/tasks/task_123"))
+	assert.Equal(t, "DELETE /v1/webhooks/{id}", breakerEndpoint("DELETE", "/v1/webhooks/wh_1"))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"id":"task_1","status":"running"}`))
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	var transitions []string
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 4,
+		OpenTimeout: 10 * time.Second,
+		Now:         func() time.Time { return now },
+		OnStateChange: func(endpoint string, from, to CircuitState) {
+			transitions = append(transitions, endpoint+" "+from.String()+"->"+to.String())
+		},
+	}))
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err := client.GetTask("task_1")
+		require.Error(t, err)
+	}
+	assert.Equal(t, CircuitOpen, client.CircuitState("GET /v1/tasks/{id}"))
+
+	// Open: fails fast without reaching the server.
+	_, err = client.GetTask("task_2")
+	var openErr *CircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	assert.Equal(t, 10*time.Second, openErr.RetryAfter)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&hits))
+
+	// Other endpoints are unaffected.
+	_, err = client.ListFiles()
+	assert.False(t, errors.As(err, &openErr))
+
+	// After the timeout a failed probe re-opens the circuit.
+	now = now.Add(11 * time.Second)
+	_, err = client.GetTask("task_1")
+	assert.False(t, errors.As(err, &openErr))
+	assert.Equal(t, CircuitOpen, client.CircuitState("GET /v1/tasks/{id}"))
+
+	// A successful probe closes it.
+	healthy.Store(true)
+	now = now.Add(11 * time.Second)
+	_, err = client.GetTask("task_1")
+	require.NoError(t, err)
+	assert.Equal(t, CircuitClosed, client.CircuitState("GET /v1/tasks/{id}"))
+
+	assert.Equal(t, []string{
+		"GET /v1/tasks/{id} closed->open",
+		"GET /v1/tasks/{id} open->half-open",
+		"GET /v1/tasks/{id} half-open->open",
+		"GET /v1/tasks/{id} open->half-open",
+		"GET /v1/tasks/{id} half-open->closed",
+	}, transitions)
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 2}))
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err := client.GetTask("missing")
+		require.Error(t, err)
+	}
+	assert.Equal(t, CircuitClosed, client.CircuitState("GET /v1/tasks/{id}"))
+}
+
+func TestCircuitBreakerIgnoresLocalFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`not json`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 2}))
+	require.NoError(t, err)
+
+	broken := NewDataAttachment("a.bin", "application/octet-stream", 4, func() (io.ReadCloser, error) {
+		return nil, errors.New("disk gone")
+	})
+	for i := 0; i < 5; i++ {
+		_, err := client.GetTask("task_1")
+		require.Error(t, err, "undecodable response")
+		_, err = client.CreateTask("Go", &TaskOptions{Attachments: []interface{}{broken}})
+		require.Error(t, err, "attachment cannot be read")
+		err = client.request("POST", "/v1/tasks", map[string]interface{}{"bad": func() {}}, nil, nil)
+		require.Error(t, err, "body cannot be encoded")
+	}
+	assert.Equal(t, CircuitClosed, client.CircuitState("GET /v1/tasks/{id}"))
+	assert.Equal(t, CircuitClosed, client.CircuitState("POST /v1/tasks"))
+
+	assert.True(t, isServerFailure(&ManusAIError{Err: &transportError{err: errors.New("connection refused")}}))
+	assert.True(t, isServerFailure(&ManusAIError{StatusCode: http.StatusTooManyRequests}))
+	assert.False(t, isServerFailure(&ManusAIError{Message: "Failed to decode response"}))
+}
+
+func TestCircuitBreakerCallbackCanUseClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var client *Client
+	var seen []CircuitState
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 1,
+		OnStateChange: func(endpoint string, from, to CircuitState) {
+			seen = append(seen, client.CircuitState(endpoint))
+		},
+	}))
+	require.NoError(t, err)
+
+	_, err = client.GetTask("task_1")
+	require.Error(t, err)
+	assert.Equal(t, []CircuitState{CircuitOpen}, seen)
+}
+
+func TestCircuitBreakerProbeOutcomes(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newCircuitBreaker(CircuitBreakerConfig{MinRequests: 1, OpenTimeout: time.Second, Now: func() time.Time { return now }})
+	const endpoint = "GET /v1/tasks/{id}"
+	serverErr := &ManusAIError{StatusCode: http.StatusBadGateway}
+
+	// A request admitted while closed that finishes after the circuit opened
+	// is not counted against the later state.
+	slow, err := b.allow(endpoint)
+	require.NoError(t, err)
+	gen, err := b.allow(endpoint)
+	require.NoError(t, err)
+	b.record(context.Background(), endpoint, gen, serverErr)
+	require.Equal(t, CircuitOpen, b.endpoints[endpoint].state)
+
+	now = now.Add(2 * time.Second)
+	probe, err := b.allow(endpoint)
+	require.NoError(t, err)
+	b.record(context.Background(), endpoint, slow, nil)
+	assert.Equal(t, 1, b.endpoints[endpoint].probes)
+
+	// A probe whose caller gave up is neither a success nor a failure.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, endpoint, probe, nil)
+	assert.Equal(t, CircuitHalfOpen, b.endpoints[endpoint].state)
+	assert.Equal(t, 0, b.endpoints[endpoint].probes)
+
+	probe, err = b.allow(endpoint)
+	require.NoError(t, err)
+	b.record(context.Background(), endpoint, probe, nil)
+	assert.Equal(t, CircuitClosed, b.endpoints[endpoint].state)
+}
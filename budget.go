@@ -0,0 +1,438 @@
+package manusai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	BudgetScopeTotal  = "total"
+	BudgetScopeWindow = "window"
+	BudgetScopeTag    = "tag"
+)
+
+type BudgetConfig struct {
+	Limit       float64
+	WindowLimit float64
+	Window      time.Duration
+	TagLimits   map[string]float64
+	Thresholds  []float64
+	OnThreshold func(event BudgetThresholdEvent)
+	Store       BudgetStore
+	Now         func() time.Time
+}
+
+type BudgetThresholdEvent struct {
+	Scope     string
+	Tag       string
+	Threshold float64
+	Spent     float64
+	Limit     float64
+}
+
+type BudgetSpend struct {
+	TaskID  string    `json:"task_id"`
+	Credits float64   `json:"credits"`
+	At      time.Time `json:"at"`
+}
+
+type BudgetState struct {
+	Total    float64             `json:"total"`
+	Tasks    map[string]float64  `json:"tasks"`
+	Tags     map[string]float64  `json:"tags"`
+	TaskTags map[string][]string `json:"task_tags"`
+	Spend    []BudgetSpend       `json:"spend,omitempty"`
+	Fired    map[string]bool     `json:"fired,omitempty"`
+}
+
+type BudgetStore interface {
+	Load() (*BudgetState, error)
+	Save(state *BudgetState) error
+}
+
+type Budget struct {
+	mu     sync.Mutex
+	config BudgetConfig
+	state  *BudgetState
+}
+
+func NewBudget(config BudgetConfig) (*Budget, error) {
+	for _, t := range config.Thresholds {
+		if t <= 0 || t > 1 {
+			return nil, &ValidationError{Message: fmt.Sprintf("Budget threshold must be in (0, 1], got %v", t)}
+		}
+	}
+	if config.WindowLimit > 0 && config.Window <= 0 {
+		return nil, &ValidationError{Message: "Budget window must be set when a window limit is configured"}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryBudgetStore()
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	state, err := config.Store.Load()
+	if err != nil {
+		return nil, &ManusAIError{Message: fmt.Sprintf("Failed to load budget state: %v", err), Err: err}
+	}
+
+	b := &Budget{config: config, state: normalizeBudgetState(state)}
+	return b, nil
+}
+
+func WithBudget(budget *Budget) ClientOption {
+	return func(c *Client) {
+		c.budget = budget
+	}
+}
+
+func (b *Budget) Check(tags []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pruneWindow()
+
+	if b.config.Limit > 0 && b.state.Total >= b.config.Limit {
+		return &BudgetExceededError{Scope: BudgetScopeTotal, Spent: b.state.Total, Limit: b.config.Limit}
+	}
+	if b.config.WindowLimit > 0 {
+		if spent := b.windowSpent(); spent >= b.config.WindowLimit {
+			return &BudgetExceededError{Scope: BudgetScopeWindow, Spent: spent, Limit: b.config.WindowLimit}
+		}
+	}
+	for _, tag := range tags {
+		limit, ok := b.config.TagLimits[tag]
+		if !ok || limit <= 0 {
+			continue
+		}
+		if spent := b.state.Tags[tag]; spent >= limit {
+			return &BudgetExceededError{Scope: BudgetScopeTag, Tag: tag, Spent: spent, Limit: limit}
+		}
+	}
+
+	return nil
+}
+
+func (b *Budget) TrackTask(taskID string, tags []string) error {
+	if taskID == "" {
+		return &ValidationError{Message: "Task ID cannot be empty"}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.state.Tasks[taskID]; !ok {
+		b.state.Tasks[taskID] = 0
+	}
+	if len(tags) > 0 {
+		b.state.TaskTags[taskID] = mergeTags(b.state.TaskTags[taskID], tags)
+	}
+
+	return b.config.Store.Save(b.state)
+}
+
+// RecordUsage records the cumulative credit usage reported for a task. Only
+// the increase since the previous report is counted, so the same task can be
+// recorded repeatedly from polling and webhooks.
+func (b *Budget) RecordUsage(taskID string, creditUsage float64) error {
+	if taskID == "" {
+		return &ValidationError{Message: "Task ID cannot be empty"}
+	}
+
+	b.mu.Lock()
+	var events []BudgetThresholdEvent
+	delta := creditUsage - b.state.Tasks[taskID]
+	if delta <= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+
+	now := b.config.Now()
+	b.state.Tasks[taskID] = creditUsage
+	b.state.Total += delta
+	for _, tag := range b.state.TaskTags[taskID] {
+		b.state.Tags[tag] += delta
+	}
+	if b.config.Window > 0 {
+		b.state.Spend = append(b.state.Spend, BudgetSpend{TaskID: taskID, Credits: delta, At: now})
+	}
+	b.pruneWindow()
+	events = b.evaluateThresholds()
+	err := b.config.Store.Save(b.state)
+	b.mu.Unlock()
+
+	if b.config.OnThreshold != nil {
+		for _, event := range events {
+			b.config.OnThreshold(event)
+		}
+	}
+
+	return err
+}
+
+func (b *Budget) RecordTaskDetail(detail *TaskDetail) error {
+	if detail == nil {
+		return nil
+	}
+	return b.RecordUsage(detail.ID, detail.CreditUsage)
+}
+
+func (b *Budget) RecordWebhook(payload *WebhookPayload) error {
+	if payload == nil || payload.TaskDetail == nil {
+		return nil
+	}
+
+	taskID, _ := payload.TaskDetail["task_id"].(string)
+	credits, ok := payload.TaskDetail["credit_usage"].(float64)
+	if taskID == "" || !ok {
+		return nil
+	}
+
+	return b.RecordUsage(taskID, credits)
+}
+
+func (b *Budget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.Total
+}
+
+func (b *Budget) SpentByTask(taskID string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.Tasks[taskID]
+}
+
+func (b *Budget) SpentByTag(tag string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.Tags[tag]
+}
+
+func (b *Budget) SpentInWindow() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pruneWindow()
+	return b.windowSpent()
+}
+
+func (b *Budget) Reset() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = normalizeBudgetState(nil)
+	return b.config.Store.Save(b.state)
+}
+
+func (b *Budget) pruneWindow() {
+	if b.config.Window <= 0 || len(b.state.Spend) == 0 {
+		return
+	}
+
+	cutoff := b.config.Now().Add(-b.config.Window)
+	i := 0
+	for i < len(b.state.Spend) && !b.state.Spend[i].At.After(cutoff) {
+		i++
+	}
+	b.state.Spend = b.state.Spend[i:]
+}
+
+func (b *Budget) windowSpent() float64 {
+	var spent float64
+	for _, s := range b.state.Spend {
+		spent += s.Credits
+	}
+	return spent
+}
+
+func (b *Budget) evaluateThresholds() []BudgetThresholdEvent {
+	var events []BudgetThresholdEvent
+
+	check := func(scope, tag string, spent, limit float64) {
+		if limit <= 0 {
+			return
+		}
+		for _, t := range b.config.Thresholds {
+			key := fmt.Sprintf("%s:%s:%g", scope, tag, t)
+			reached := spent >= limit*t
+			if reached && !b.state.Fired[key] {
+				b.state.Fired[key] = true
+				events = append(events, BudgetThresholdEvent{Scope: scope, Tag: tag, Threshold: t, Spent: spent, Limit: limit})
+			} else if !reached && b.state.Fired[key] {
+				delete(b.state.Fired, key)
+			}
+		}
+	}
+
+	check(BudgetScopeTotal, "", b.state.Total, b.config.Limit)
+	check(BudgetScopeWindow, "", b.windowSpent(), b.config.WindowLimit)
+
+	tags := make([]string, 0, len(b.config.TagLimits))
+	for tag := range b.config.TagLimits {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		check(BudgetScopeTag, tag, b.state.Tags[tag], b.config.TagLimits[tag])
+	}
+
+	return events
+}
+
+func normalizeBudgetState(state *BudgetState) *BudgetState {
+	if state == nil {
+		state = &BudgetState{}
+	}
+	if state.Tasks == nil {
+		state.Tasks = make(map[string]float64)
+	}
+	if state.Tags == nil {
+		state.Tags = make(map[string]float64)
+	}
+	if state.TaskTags == nil {
+		state.TaskTags = make(map[string][]string)
+	}
+	if state.Fired == nil {
+		state.Fired = make(map[string]bool)
+	}
+	return state
+}
+
+func mergeTags(existing, tags []string) []string {
+	seen := make(map[string]bool, len(existing))
+	for _, tag := range existing {
+		seen[tag] = true
+	}
+	for _, tag := range tags {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			existing = append(existing, tag)
+		}
+	}
+	return existing
+}
+
+type MemoryBudgetStore struct {
+	mu    sync.Mutex
+	state []byte
+}
+
+func NewMemoryBudgetStore() *MemoryBudgetStore {
+	return &MemoryBudgetStore{}
+}
+
+func (s *MemoryBudgetStore) Load() (*BudgetState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state == nil {
+		return nil, nil
+	}
+
+	var state BudgetState
+	if err := json.Unmarshal(s.state, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s *MemoryBudgetStore) Save(state *BudgetState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.state = data
+	s.mu.Unlock()
+	return nil
+}
+
+type FileBudgetStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileBudgetStore(path string) *FileBudgetStore {
+	return &FileBudgetStore{path: path}
+}
+
+func (s *FileBudgetStore) Load() (*BudgetState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state BudgetState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid budget file %s: %w", s.path, err)
+	}
+	return &state, nil
+}
+
+func (s *FileBudgetStore) Save(state *BudgetState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFileAtomic(s.path, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	return replaceFile(path, data, false)
+}
+
+// writeFileDurable is writeFileAtomic for files that must survive a crash:
+// the data is synced before the rename and the directory after it.
+func writeFileDurable(path string, data []byte) error {
+	return replaceFile(path, data, true)
+}
+
+func replaceFile(path string, data []byte, durable bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if durable {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if !durable {
+		return nil
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,140 @@
+package manusai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetRecordUsage(t *testing.T) {
+	budget, err := NewBudget(BudgetConfig{})
+	require.NoError(t, err)
+
+	require.NoError(t, budget.TrackTask("task_1", []string{"team-a"}))
+	require.NoError(t, budget.RecordUsage("task_1", 2))
+	require.NoError(t, budget.RecordUsage("task_1", 5))
+	require.NoError(t, budget.RecordUsage("task_1", 5))
+	require.NoError(t, budget.RecordUsage("task_2", 1))
+
+	assert.Equal(t, 6.0, budget.Spent())
+	assert.Equal(t, 5.0, budget.SpentByTask("task_1"))
+	assert.Equal(t, 5.0, budget.SpentByTag("team-a"))
+}
+
+func TestBudgetCheck(t *testing.T) {
+	t.Run("total limit", func(t *testing.T) {
+		budget, err := NewBudget(BudgetConfig{Limit: 10})
+		require.NoError(t, err)
+
+		assert.NoError(t, budget.Check(nil))
+		require.NoError(t, budget.RecordUsage("task_1", 10))
+
+		err = budget.Check(nil)
+		require.Error(t, err)
+		var exceeded *BudgetExceededError
+		require.ErrorAs(t, err, &exceeded)
+		assert.Equal(t, BudgetScopeTotal, exceeded.Scope)
+	})
+
+	t.Run("tag limit", func(t *testing.T) {
+		budget, err := NewBudget(BudgetConfig{TagLimits: map[string]float64{"team-a": 3}})
+		require.NoError(t, err)
+
+		require.NoError(t, budget.TrackTask("task_1", []string{"team-a"}))
+		require.NoError(t, budget.RecordUsage("task_1", 4))
+
+		assert.Error(t, budget.Check([]string{"team-a"}))
+		assert.NoError(t, budget.Check([]string{"team-b"}))
+	})
+
+	t.Run("window limit", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		budget, err := NewBudget(BudgetConfig{
+			WindowLimit: 5,
+			Window:      time.Hour,
+			Now:         func() time.Time { return now },
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, budget.RecordUsage("task_1", 5))
+		assert.Error(t, budget.Check(nil))
+
+		now = now.Add(2 * time.Hour)
+		assert.NoError(t, budget.Check(nil))
+		assert.Equal(t, 0.0, budget.SpentInWindow())
+	})
+}
+
+func TestBudgetThresholds(t *testing.T) {
+	var events []BudgetThresholdEvent
+	budget, err := NewBudget(BudgetConfig{
+		Limit:       10,
+		Thresholds:  []float64{0.5, 0.8},
+		OnThreshold: func(event BudgetThresholdEvent) { events = append(events, event) },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, budget.RecordUsage("task_1", 4))
+	assert.Empty(t, events)
+
+	require.NoError(t, budget.RecordUsage("task_1", 6))
+	require.Len(t, events, 1)
+	assert.Equal(t, 0.5, events[0].Threshold)
+
+	require.NoError(t, budget.RecordUsage("task_1", 9))
+	require.Len(t, events, 2)
+	assert.Equal(t, 0.8, events[1].Threshold)
+
+	_, err = NewBudget(BudgetConfig{Thresholds: []float64{1.5}})
+	assert.Error(t, err)
+}
+
+func TestFileBudgetStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.json")
+
+	budget, err := NewBudget(BudgetConfig{Store: NewFileBudgetStore(path)})
+	require.NoError(t, err)
+	require.NoError(t, budget.TrackTask("task_1", []string{"team-a"}))
+	require.NoError(t, budget.RecordUsage("task_1", 3))
+
+	reloaded, err := NewBudget(BudgetConfig{Store: NewFileBudgetStore(path)})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, reloaded.Spent())
+	assert.Equal(t, 3.0, reloaded.SpentByTag("team-a"))
+}
+
+func TestClientWithBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(`{"task_id":"task_123"}`))
+			return
+		}
+		w.Write([]byte(`{"id":"task_123","status":"completed","credit_usage":12}`))
+	}))
+	defer server.Close()
+
+	budget, err := NewBudget(BudgetConfig{Limit: 10})
+	require.NoError(t, err)
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL), WithBudget(budget))
+
+	_, err = client.CreateTask("Test prompt", &TaskOptions{Tags: []string{"team-a"}})
+	require.NoError(t, err)
+
+	_, err = client.GetTask("task_123")
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, budget.Spent())
+
+	_, err = client.CreateTask("Test prompt", nil)
+	assert.IsType(t, &BudgetExceededError{}, err)
+
+	client.ObserveWebhook(&WebhookPayload{
+		EventType:  "task_stopped",
+		TaskDetail: map[string]interface{}{"task_id": "task_456", "credit_usage": 2.0},
+	})
+	assert.Equal(t, 14.0, budget.Spent())
+}
@@ -1,10 +1,12 @@
 package manusai
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -12,15 +14,30 @@ import (
 )
 
 const (
-	DefaultBaseURL       = "https://api.manus.ai"
-	DefaultTimeout       = 30 * time.Second
+	DefaultBaseURL        = "https://api.manus.ai"
+	DefaultTimeout        = 30 * time.Second
 	DefaultConnectTimeout = 10 * time.Second
 )
 
 type Client struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	credentials      CredentialsProvider
+	breaker          *circuitBreaker
+	idempotency      idempotencyCache
+	baseURL          string
+	httpClient       *http.Client
+	transportConfig  *TransportConfig
+	upload           UploadConfig
+	budget           *Budget
+	taskStore        TaskStore
+	await            AwaitConfig
+	fileWait         FileWaitConfig
+	inlineLimit      int64
+	attachmentPolicy AttachmentPolicy
+	profiles         *AgentProfileRegistry
+	deprecation      DeprecationPolicy
+	logger           *slog.Logger
+	waiters          taskWaiters
 }
 
 type ClientOption func(*Client)
@@ -44,38 +61,145 @@ func WithTimeout(timeout time.Duration) ClientOption {
 }
 
 func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
-	if strings.TrimSpace(apiKey) == "" {
-		return nil, &AuthenticationError{Message: "API key cannot be empty"}
-	}
-
 	client := &Client{
 		apiKey:  apiKey,
 		baseURL: DefaultBaseURL,
 		httpClient: &http.Client{
-			Timeout: DefaultTimeout,
+			Timeout:   DefaultTimeout,
+			Transport: defaultTransport(),
+		},
+		await: AwaitConfig{
+			WebhookTimeout: DefaultAwaitWebhookTimeout,
+			PollInterval:   DefaultAwaitPollInterval,
+		},
+		fileWait: FileWaitConfig{
+			Interval:    DefaultFileWaitInterval,
+			MaxInterval: DefaultFileWaitMaxInterval,
+			Timeout:     DefaultFileWaitTimeout,
 		},
+		inlineLimit:      DefaultInlineAttachmentLimit,
+		attachmentPolicy: DefaultAttachmentPolicy(),
+		logger:           slog.Default(),
 	}
 
 	for _, opt := range opts {
 		opt(client)
 	}
 
+	if client.transportConfig != nil {
+		httpClient, err := applyTransportConfig(client.httpClient, client.transportConfig)
+		if err != nil {
+			return nil, err
+		}
+		client.httpClient = httpClient
+	}
+
+	if client.profiles == nil {
+		client.profiles = NewAgentProfileRegistry(nil, 0)
+	}
+
+	if client.credentials == nil {
+		if strings.TrimSpace(apiKey) == "" {
+			return nil, &AuthenticationError{Message: "API key cannot be empty"}
+		}
+		client.credentials = StaticCredentials(apiKey)
+	}
+
 	return client, nil
 }
 
 func (c *Client) CreateTask(prompt string, options *TaskOptions) (*TaskResponse, error) {
+	return c.createTask(context.Background(), prompt, options)
+}
+
+func (c *Client) createTask(ctx context.Context, prompt string, options *TaskOptions) (*TaskResponse, error) {
 	if strings.TrimSpace(prompt) == "" {
 		return nil, &ValidationError{Message: "Task prompt cannot be empty"}
 	}
 
+	if options != nil {
+		if err := c.attachmentPolicy.check(options.Attachments, c.inlineLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []string
+	var explicitKey string
+	if options != nil {
+		tags = options.Tags
+		explicitKey = options.IdempotencyKey
+	}
+
+	key := c.idempotency.key(explicitKey)
+	if key == "" {
+		return c.postTask(ctx, prompt, options, tags, "")
+	}
+
+	resource, err := c.idempotency.do(ctx, "task:"+key, func() (string, interface{}, error) {
+		result, err := c.postTask(ctx, prompt, options, tags, key)
+		if err != nil {
+			return "", nil, err
+		}
+		return result.TaskID, result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := *resource.(*TaskResponse)
+	return &resp, nil
+}
+
+// postTask creates the task once the idempotency key, if any, is claimed.
+// Files uploaded for large data attachments are deleted again if the task is
+// not created, unless the POST itself failed ambiguously and the task may
+// exist.
+func (c *Client) postTask(ctx context.Context, prompt string, options *TaskOptions, tags []string, key string) (_ *TaskResponse, err error) {
+	if c.budget != nil {
+		if err := c.budget.Check(tags); err != nil {
+			return nil, err
+		}
+	}
+
+	var attachments []interface{}
+	var uploaded []string
+	posted := false
+	if options != nil {
+		attachments, uploaded, err = c.uploadLargeAttachments(ctx, options.Attachments)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if err != nil && !(posted && isAmbiguousFailure(err)) {
+			c.discardFiles(uploaded)
+		}
+	}()
+
+	if options != nil && options.WaitForFiles {
+		for _, fileID := range attachmentFileIDs(attachments) {
+			if _, err := c.WaitForFile(ctx, fileID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	payload := map[string]interface{}{
 		"prompt":       prompt,
 		"agentProfile": "manus-1.6",
 	}
 
+	var requested string
 	if options != nil {
-		if options.AgentProfile != "" {
-			payload["agentProfile"] = options.AgentProfile
+		requested = options.AgentProfile
+		if requested == "" && options.ProfileSelector != nil {
+			requested = options.ProfileSelector.SelectProfile(newProfileRequest(prompt, options))
+		}
+		if requested != "" {
+			resolved, err := c.resolveAgentProfile(requested)
+			if err != nil {
+				return nil, err
+			}
+			payload["agentProfile"] = resolved
 		}
 		if options.TaskMode != "" {
 			payload["taskMode"] = options.TaskMode
@@ -89,21 +213,60 @@ func (c *Client) CreateTask(prompt string, options *TaskOptions) (*TaskResponse,
 		if options.CreateShareableLink != nil {
 			payload["createShareableLink"] = *options.CreateShareableLink
 		}
-		if options.Attachments != nil && len(options.Attachments) > 0 {
-			payload["attachments"] = options.Attachments
+		if len(attachments) > 0 {
+			payload["attachments"] = attachments
 		}
 	}
 
 	var result TaskResponse
-	err := c.request("POST", "/v1/tasks", payload, nil, &result)
+	posted = true
+	err = c.submitIdempotent(ctx, key,
+		func(ctx context.Context) error {
+			return c.requestContext(ctx, "POST", "/v1/tasks", payload, nil, &result)
+		},
+		func(ctx context.Context, since time.Time) (bool, error) {
+			recovered, err := c.recoverTask(ctx, prompt, since)
+			if recovered != nil {
+				result = *recovered
+			}
+			return recovered != nil, err
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	result.AgentProfile, _ = payload["agentProfile"].(string)
+	result.requestedProfile = requested
+
+	if c.budget != nil {
+		_ = c.budget.TrackTask(result.TaskID, tags)
+	}
+	if c.taskStore != nil {
+		now := time.Now().UTC()
+		record := &TaskRecord{
+			TaskID:     result.TaskID,
+			PromptHash: HashPrompt(prompt),
+			Tags:       tags,
+			Status:     TaskStatusPending,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if options != nil {
+			record.Labels = options.Labels
+			record.FileIDs = attachmentFileIDs(attachments)
+		}
+		_ = c.taskStore.Put(record)
+	}
+
 	return &result, nil
 }
 
 func (c *Client) GetTasks(filters *TaskFilters) (*TaskListResponse, error) {
+	return c.getTasks(context.Background(), filters)
+}
+
+func (c *Client) getTasks(ctx context.Context, filters *TaskFilters) (*TaskListResponse, error) {
 	query := url.Values{}
 
 	if filters != nil {
@@ -136,25 +299,37 @@ func (c *Client) GetTasks(filters *TaskFilters) (*TaskListResponse, error) {
 	}
 
 	var result TaskListResponse
-	err := c.request("GET", "/v1/tasks", nil, query, &result)
+	err := c.requestContext(ctx, "GET", "/v1/tasks", nil, query, &result)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.taskStore != nil {
+		for _, summary := range result.Data {
+			c.updateStoredStatus(summary.ID, summary.Status, "", nil)
+		}
+	}
+
 	return &result, nil
 }
 
 func (c *Client) GetTask(taskID string) (*TaskDetail, error) {
+	return c.getTask(context.Background(), taskID)
+}
+
+func (c *Client) getTask(ctx context.Context, taskID string) (*TaskDetail, error) {
 	if strings.TrimSpace(taskID) == "" {
 		return nil, &ValidationError{Message: "Task ID cannot be empty"}
 	}
 
 	var result TaskDetail
-	err := c.request("GET", fmt.Sprintf("/v1/tasks/%s", taskID), nil, nil, &result)
+	err := c.requestContext(ctx, "GET", fmt.Sprintf("/v1/tasks/%s", taskID), nil, nil, &result)
 	if err != nil {
 		return nil, err
 	}
 
+	c.observeTaskDetail(&result)
+
 	return &result, nil
 }
 
@@ -193,6 +368,8 @@ func (c *Client) UpdateTask(taskID string, updates *TaskUpdate) (*TaskDetail, er
 		return nil, err
 	}
 
+	c.observeTaskDetail(&result)
+
 	return &result, nil
 }
 
@@ -211,59 +388,69 @@ func (c *Client) DeleteTask(taskID string) (*DeleteResponse, error) {
 }
 
 func (c *Client) CreateFile(filename string) (*FileResponse, error) {
+	return c.CreateFileWithOptions(filename, nil)
+}
+
+// CreateFileWithOptions creates a file record. With an idempotency key, a
+// repeated call returns the cached record and a lost response is retried
+// once with the same key.
+func (c *Client) CreateFileWithOptions(filename string, options *FileOptions) (*FileResponse, error) {
+	return c.createFile(context.Background(), filename, options)
+}
+
+func (c *Client) createFile(ctx context.Context, filename string, options *FileOptions) (*FileResponse, error) {
 	if strings.TrimSpace(filename) == "" {
 		return nil, &ValidationError{Message: "Filename cannot be empty"}
 	}
 
-	payload := map[string]string{
-		"filename": filename,
+	var explicitKey string
+	if options != nil {
+		explicitKey = options.IdempotencyKey
+	}
+	key := c.idempotency.key(explicitKey)
+	if key == "" {
+		return c.postFile(ctx, filename, "")
 	}
 
-	var result FileResponse
-	err := c.request("POST", "/v1/files", payload, nil, &result)
+	resource, err := c.idempotency.do(ctx, "file:"+key, func() (string, interface{}, error) {
+		result, err := c.postFile(ctx, filename, key)
+		if err != nil {
+			return "", nil, err
+		}
+		return result.ID, result, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return &result, nil
+	resp := *resource.(*FileResponse)
+	return &resp, nil
 }
 
-func (c *Client) UploadFileContent(uploadURL string, fileContent []byte, contentType string) error {
-	if strings.TrimSpace(uploadURL) == "" {
-		return &ValidationError{Message: "Upload URL cannot be empty"}
-	}
-
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
-	req, err := http.NewRequest("PUT", uploadURL, bytes.NewReader(fileContent))
-	if err != nil {
-		return &ManusAIError{Message: fmt.Sprintf("Failed to create upload request: %v", err)}
+func (c *Client) postFile(ctx context.Context, filename, key string) (*FileResponse, error) {
+	payload := map[string]string{
+		"filename": filename,
 	}
 
-	req.Header.Set("Content-Type", contentType)
-
-	resp, err := c.httpClient.Do(req)
+	var result FileResponse
+	err := c.submitIdempotent(ctx, key,
+		func(ctx context.Context) error {
+			return c.requestContext(ctx, "POST", "/v1/files", payload, nil, &result)
+		},
+		nil,
+	)
 	if err != nil {
-		return &ManusAIError{Message: fmt.Sprintf("Failed to upload file content: %v", err)}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return &ManusAIError{
-			Message:    fmt.Sprintf("Upload failed with status %d: %s", resp.StatusCode, string(body)),
-			StatusCode: resp.StatusCode,
-		}
+		return nil, err
 	}
-
-	return nil
+	return &result, nil
 }
 
 func (c *Client) ListFiles() (*FileListResponse, error) {
+	return c.listFiles(context.Background())
+}
+
+func (c *Client) listFiles(ctx context.Context) (*FileListResponse, error) {
 	var result FileListResponse
-	err := c.request("GET", "/v1/files", nil, nil, &result)
+	err := c.requestContext(ctx, "GET", "/v1/files", nil, nil, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -272,12 +459,16 @@ func (c *Client) ListFiles() (*FileListResponse, error) {
 }
 
 func (c *Client) GetFile(fileID string) (*FileDetail, error) {
+	return c.getFile(context.Background(), fileID)
+}
+
+func (c *Client) getFile(ctx context.Context, fileID string) (*FileDetail, error) {
 	if strings.TrimSpace(fileID) == "" {
 		return nil, &ValidationError{Message: "File ID cannot be empty"}
 	}
 
 	var result FileDetail
-	err := c.request("GET", fmt.Sprintf("/v1/files/%s", fileID), nil, nil, &result)
+	err := c.requestContext(ctx, "GET", fmt.Sprintf("/v1/files/%s", fileID), nil, nil, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -286,12 +477,16 @@ func (c *Client) GetFile(fileID string) (*FileDetail, error) {
 }
 
 func (c *Client) DeleteFile(fileID string) (*DeleteResponse, error) {
+	return c.deleteFile(context.Background(), fileID)
+}
+
+func (c *Client) deleteFile(ctx context.Context, fileID string) (*DeleteResponse, error) {
 	if strings.TrimSpace(fileID) == "" {
 		return nil, &ValidationError{Message: "File ID cannot be empty"}
 	}
 
 	var result DeleteResponse
-	err := c.request("DELETE", fmt.Sprintf("/v1/files/%s", fileID), nil, nil, &result)
+	err := c.requestContext(ctx, "DELETE", fmt.Sprintf("/v1/files/%s", fileID), nil, nil, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -300,6 +495,10 @@ func (c *Client) DeleteFile(fileID string) (*DeleteResponse, error) {
 }
 
 func (c *Client) CreateWebhook(webhook *WebhookConfig) (*WebhookResponse, error) {
+	return c.createWebhook(context.Background(), webhook)
+}
+
+func (c *Client) createWebhook(ctx context.Context, webhook *WebhookConfig) (*WebhookResponse, error) {
 	if webhook == nil {
 		return nil, &ValidationError{Message: "Webhook configuration cannot be nil"}
 	}
@@ -313,7 +512,35 @@ func (c *Client) CreateWebhook(webhook *WebhookConfig) (*WebhookResponse, error)
 	}
 
 	var result WebhookResponse
-	err := c.request("POST", "/v1/webhooks", payload, nil, &result)
+	err := c.requestContext(ctx, "POST", "/v1/webhooks", payload, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Client) ListWebhooks() (*WebhookListResponse, error) {
+	return c.listWebhooks(context.Background())
+}
+
+func (c *Client) listWebhooks(ctx context.Context) (*WebhookListResponse, error) {
+	var result WebhookListResponse
+	err := c.requestContext(ctx, "GET", "/v1/webhooks", nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Client) GetWebhook(webhookID string) (*WebhookDetail, error) {
+	if strings.TrimSpace(webhookID) == "" {
+		return nil, &ValidationError{Message: "Webhook ID cannot be empty"}
+	}
+
+	var result WebhookDetail
+	err := c.request("GET", fmt.Sprintf("/v1/webhooks/%s", webhookID), nil, nil, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -321,56 +548,179 @@ func (c *Client) CreateWebhook(webhook *WebhookConfig) (*WebhookResponse, error)
 	return &result, nil
 }
 
+func (c *Client) UpdateWebhook(webhookID string, webhook *WebhookConfig) (*WebhookDetail, error) {
+	return c.updateWebhook(context.Background(), webhookID, webhook)
+}
+
+func (c *Client) updateWebhook(ctx context.Context, webhookID string, webhook *WebhookConfig) (*WebhookDetail, error) {
+	if strings.TrimSpace(webhookID) == "" {
+		return nil, &ValidationError{Message: "Webhook ID cannot be empty"}
+	}
+
+	if webhook == nil {
+		return nil, &ValidationError{Message: "Webhook configuration cannot be nil"}
+	}
+
+	if webhook.URL == "" {
+		return nil, &ValidationError{Message: "Webhook URL is required"}
+	}
+
+	payload := map[string]interface{}{
+		"webhook": webhook,
+	}
+
+	var result WebhookDetail
+	err := c.requestContext(ctx, "PATCH", fmt.Sprintf("/v1/webhooks/%s", webhookID), payload, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.WebhookID == "" {
+		result.WebhookID = webhookID
+		result.URL = webhook.URL
+		result.Events = webhook.Events
+	}
+
+	return &result, nil
+}
+
 func (c *Client) DeleteWebhook(webhookID string) error {
+	return c.deleteWebhook(context.Background(), webhookID)
+}
+
+func (c *Client) deleteWebhook(ctx context.Context, webhookID string) error {
 	if strings.TrimSpace(webhookID) == "" {
 		return &ValidationError{Message: "Webhook ID cannot be empty"}
 	}
 
-	err := c.request("DELETE", fmt.Sprintf("/v1/webhooks/%s", webhookID), nil, nil, nil)
+	err := c.requestContext(ctx, "DELETE", fmt.Sprintf("/v1/webhooks/%s", webhookID), nil, nil, nil)
 	return err
 }
 
+// ObserveWebhook feeds a received webhook payload into the client's local
+// bookkeeping, such as the credit budget, and resolves pending TaskHandles.
+func (c *Client) ObserveWebhook(payload *WebhookPayload) {
+	if payload == nil {
+		return
+	}
+	if IsTaskStopped(payload) && payload.TaskDetail != nil {
+		if taskID, _ := payload.TaskDetail["task_id"].(string); taskID != "" {
+			c.waiters.resolve(taskID, payload)
+		}
+	}
+	if c.budget != nil {
+		_ = c.budget.RecordWebhook(payload)
+	}
+	if c.taskStore != nil {
+		taskID, _ := payload.TaskDetail["task_id"].(string)
+		stopReason, _ := payload.TaskDetail["stop_reason"].(string)
+		switch {
+		case IsTaskCreated(payload):
+			c.updateStoredStatus(taskID, TaskStatusRunning, "", nil)
+		case IsTaskCompleted(payload):
+			c.updateStoredStatus(taskID, TaskStatusCompleted, stopReason, nil)
+		case IsTaskStopped(payload):
+			c.updateStoredStatus(taskID, TaskStatusPending, stopReason, nil)
+		}
+	}
+}
+
+func (c *Client) observeTaskDetail(detail *TaskDetail) {
+	if c.budget != nil {
+		_ = c.budget.RecordTaskDetail(detail)
+	}
+	if c.taskStore != nil {
+		c.updateStoredStatus(detail.ID, detail.Status, "", &detail.CreditUsage)
+	}
+}
+
+func (c *Client) updateStoredStatus(taskID, status, stopReason string, creditUsage *float64) {
+	if taskID == "" {
+		return
+	}
+
+	_ = c.taskStore.Update(taskID, func(record *TaskRecord) {
+		changed := false
+		if status != "" && status != record.Status {
+			record.Status = status
+			changed = true
+		}
+		if stopReason != "" && stopReason != record.StopReason {
+			record.StopReason = stopReason
+			changed = true
+		}
+		if creditUsage != nil && *creditUsage != record.CreditUsage {
+			record.CreditUsage = *creditUsage
+			changed = true
+		}
+		if changed {
+			record.UpdatedAt = time.Now().UTC()
+		}
+	})
+}
+
 func (c *Client) request(method, endpoint string, body interface{}, query url.Values, result interface{}) error {
+	return c.requestContext(context.Background(), method, endpoint, body, query, result)
+}
+
+func (c *Client) requestContext(ctx context.Context, method, endpoint string, body interface{}, query url.Values, result interface{}) error {
+	if c.breaker == nil {
+		return c.doRequest(ctx, method, endpoint, body, query, result)
+	}
+
+	key := breakerEndpoint(method, endpoint)
+	gen, err := c.breaker.allow(key)
+	if err != nil {
+		return err
+	}
+	err = c.doRequest(ctx, method, endpoint, body, query, result)
+	c.breaker.record(ctx, key, gen, err)
+	return err
+}
+
+func (c *Client) doRequest(ctx context.Context, method, endpoint string, body interface{}, query url.Values, result interface{}) error {
 	fullURL := c.baseURL + endpoint
 	if query != nil && len(query) > 0 {
 		fullURL += "?" + query.Encode()
 	}
 
-	var reqBody io.Reader
+	var reqBody *requestBody
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		var err error
+		reqBody, err = encodeRequestBody(body)
 		if err != nil {
-			return &ManusAIError{Message: fmt.Sprintf("Failed to marshal request body: %v", err)}
+			return &ManusAIError{Message: fmt.Sprintf("Failed to marshal request body: %v", err), Err: &requestEncodingError{err: err}}
 		}
-		reqBody = bytes.NewReader(jsonData)
 	}
 
-	req, err := http.NewRequest(method, fullURL, reqBody)
+	apiKey, err := c.credentials.APIKey(ctx)
 	if err != nil {
-		return &ManusAIError{Message: fmt.Sprintf("Failed to create request: %v", err)}
+		return err
 	}
 
-	req.Header.Set("Authorization", c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+	statusCode, respBody, err := c.send(ctx, method, fullURL, reqBody, apiKey)
+	if err == nil && statusCode == http.StatusUnauthorized {
+		// The key may have been rotated; re-read it and retry once.
+		if refresher, ok := c.credentials.(CredentialsRefresher); ok {
+			if err := refresher.Refresh(ctx); err != nil {
+				return err
+			}
+		}
+		if newKey, keyErr := c.credentials.APIKey(ctx); keyErr == nil && newKey != apiKey {
+			apiKey = newKey
+			statusCode, respBody, err = c.send(ctx, method, fullURL, reqBody, apiKey)
+		}
+	}
 	if err != nil {
-		return &ManusAIError{Message: fmt.Sprintf("Request failed: %v", err)}
+		return &ManusAIError{Message: redactKey(err.Error(), apiKey), Err: err}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNoContent {
+	if statusCode == http.StatusNoContent {
 		return nil
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &ManusAIError{Message: fmt.Sprintf("Failed to read response body: %v", err)}
-	}
-
-	if resp.StatusCode >= 400 {
-		return c.handleErrorResponse(resp.StatusCode, respBody)
+	if statusCode >= 400 {
+		return c.handleErrorResponse(statusCode, []byte(redactKey(string(respBody), apiKey)))
 	}
 
 	if len(respBody) == 0 {
@@ -386,6 +736,48 @@ func (c *Client) request(method, endpoint string, body interface{}, query url.Va
 	return nil
 }
 
+func (c *Client) send(ctx context.Context, method, fullURL string, body *requestBody, apiKey string) (int, []byte, error) {
+	var reqBody io.ReadCloser
+	if body != nil {
+		reqBody = body.reader()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to create request: %v", err)
+	}
+	if body != nil {
+		req.ContentLength = body.length
+		req.GetBody = func() (io.ReadCloser, error) {
+			return body.reader(), nil
+		}
+	}
+
+	req.Header.Set("Authorization", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if key := idempotencyKeyFrom(ctx); key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var readErr *attachmentReadError
+		if errors.As(err, &readErr) {
+			return 0, nil, fmt.Errorf("Failed to read attachment: %w", readErr)
+		}
+		return 0, nil, &transportError{err: fmt.Errorf("Request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, &transportError{err: fmt.Errorf("Failed to read response body: %w", err)}
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
 func (c *Client) handleErrorResponse(statusCode int, body []byte) error {
 	message := string(body)
 
@@ -175,3 +175,62 @@ func TestErrorHandling(t *testing.T) {
 		assert.IsType(t, &ValidationError{}, err)
 	})
 }
+
+func TestListWebhooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/v1/webhooks", r.URL.Path)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":[{"webhook_id":"webhook_123","url":"https://example.com/webhook","events":["task_stopped"]}]}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+
+	result, err := client.ListWebhooks()
+	require.NoError(t, err)
+	require.Len(t, result.Data, 1)
+	assert.Equal(t, "webhook_123", result.Data[0].WebhookID)
+	assert.Equal(t, []string{"task_stopped"}, result.Data[0].Events)
+}
+
+func TestGetWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/v1/webhooks/webhook_123", r.URL.Path)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"webhook_id":"webhook_123","url":"https://example.com/webhook"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+
+	result, err := client.GetWebhook("webhook_123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/webhook", result.URL)
+
+	_, err = client.GetWebhook("")
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		assert.Equal(t, "/v1/webhooks/webhook_123", r.URL.Path)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"webhook_id":"webhook_123","url":"https://example.com/new","events":["task_created"]}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+
+	result, err := client.UpdateWebhook("webhook_123", &WebhookConfig{URL: "https://example.com/new", Events: []string{"task_created"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", result.URL)
+
+	_, err = client.UpdateWebhook("webhook_123", nil)
+	assert.IsType(t, &ValidationError{}, err)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	manusai "github.com/tigusigalpa/manus-ai-go"
+)
+
+func runFile(sub string, args []string) error {
+	switch sub {
+	case "gc":
+		return runFileGC(args)
+	default:
+		return fmt.Errorf("unknown file subcommand %q", sub)
+	}
+}
+
+func runFileGC(args []string) error {
+	fs := flag.NewFlagSet("file gc", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "", "delete files created at least this long ago, e.g. 72h or 30d")
+	taskStore := fs.String("task-store", "", "task store file; files attached to active tasks are kept")
+	ignoreTasks := fs.Bool("ignore-tasks", false, "run without --task-store, even deleting files used by running tasks")
+	concurrency := fs.Int("concurrency", manusai.DefaultJanitorConcurrency, "number of parallel deletions")
+	dryRun := fs.Bool("dry-run", false, "list the files that would be deleted without deleting them")
+	var patterns stringList
+	fs.Var(&patterns, "pattern", "delete files whose name matches this glob (repeatable); with --older-than, files must match both")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *taskStore == "" && !*ignoreTasks {
+		return fmt.Errorf("--task-store is required to keep files used by running tasks; pass --ignore-tasks to delete them anyway")
+	}
+
+	config := manusai.FileJanitorConfig{
+		Patterns:    patterns,
+		IgnoreTasks: *ignoreTasks,
+		Concurrency: *concurrency,
+		DryRun:      *dryRun,
+	}
+	if *olderThan != "" {
+		retention, err := parseRetention(*olderThan)
+		if err != nil {
+			return err
+		}
+		config.Retention = retention
+	}
+	if *taskStore != "" {
+		store, err := manusai.NewFileTaskStore(*taskStore)
+		if err != nil {
+			return err
+		}
+		config.TaskStore = store
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	janitor, err := manusai.NewFileJanitor(client, config)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := janitor.Run(ctx)
+	if report == nil {
+		return err
+	}
+
+	verb := "deleted"
+	if report.DryRun {
+		verb = "would delete"
+	}
+	for _, f := range report.Deleted {
+		fmt.Printf("%s %s %s (created %s)\n", verb, f.ID, f.Filename, f.CreatedAt)
+	}
+	for _, s := range report.Skipped {
+		fmt.Printf("skipped %s %s: %s\n", s.File.ID, s.File.Filename, s.Reason)
+	}
+	for _, f := range report.Failed {
+		fmt.Fprintf(os.Stderr, "failed %s %s: %v\n", f.File.ID, f.File.Filename, f.Err)
+	}
+	fmt.Println(report.Summary())
+
+	if err != nil {
+		return err
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d files could not be deleted", len(report.Failed))
+	}
+	return nil
+}
+
+// parseRetention accepts time.ParseDuration values plus a "d" suffix for
+// days.
+func parseRetention(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid --older-than %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --older-than %q", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,105 @@
+// Command manus is a small command-line companion to the Manus AI Go SDK.
+//
+// The API key is read from the MANUS_AI_API_KEY environment variable, or from
+// the file named by MANUS_AI_API_KEY_FILE.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	manusai "github.com/tigusigalpa/manus-ai-go"
+)
+
+const usage = `Usage: manus <command> <subcommand> [flags]
+
+Commands:
+  task export <id>       Export a task transcript
+  task export [filters]  Export every task matching the filters
+  file gc                Delete old or unwanted uploaded files
+  webhook simulate       Deliver simulated webhook events to a local endpoint
+  webhook relay          Verify webhooks and fan them out to internal endpoints
+
+Run "manus <command> <subcommand> -h" for command flags.
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "manus: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "task":
+		return runTask(args[1], args[2:])
+	case "file":
+		return runFile(args[1], args[2:])
+	case "webhook":
+		return runWebhook(args[1], args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func newClient() (*manusai.Client, error) {
+	var opts []manusai.ClientOption
+
+	apiKey := os.Getenv("MANUS_AI_API_KEY")
+	if path := os.Getenv("MANUS_AI_API_KEY_FILE"); path != "" {
+		creds, err := manusai.NewFileCredentials(path)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, manusai.WithCredentials(creds))
+	} else if apiKey == "" {
+		return nil, fmt.Errorf("MANUS_AI_API_KEY or MANUS_AI_API_KEY_FILE environment variable is required")
+	}
+
+	if baseURL := os.Getenv("MANUS_AI_BASE_URL"); baseURL != "" {
+		opts = append(opts, manusai.WithBaseURL(baseURL))
+	}
+
+	return manusai.NewClient(apiKey, opts...)
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments, e.g. "export task_123 --format md".
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
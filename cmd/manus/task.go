@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	manusai "github.com/tigusigalpa/manus-ai-go"
+	"github.com/tigusigalpa/manus-ai-go/export"
+)
+
+func runTask(sub string, args []string) error {
+	switch sub {
+	case "export":
+		return runTaskExport(args)
+	default:
+		return fmt.Errorf("unknown task subcommand %q", sub)
+	}
+}
+
+func runTaskExport(args []string) error {
+	fs := flag.NewFlagSet("task export", flag.ContinueOnError)
+	format := fs.String("format", "md", "output format: md, html or json")
+	output := fs.String("output", "", "output file for a single task (default stdout)")
+	dir := fs.String("dir", ".", "output directory for bulk export")
+	query := fs.String("query", "", "bulk export: search query")
+	createdAfter := fs.String("created-after", "", "bulk export: only tasks created after this time")
+	createdBefore := fs.String("created-before", "", "bulk export: only tasks created before this time")
+	limit := fs.Int("limit", 0, "bulk export: page size")
+	var statuses stringList
+	fs.Var(&statuses, "status", "bulk export: task status filter (repeatable or comma-separated)")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	exportFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		paths, err := export.Tasks(client, &manusai.TaskFilters{
+			Query:         *query,
+			Status:        statuses,
+			CreatedAfter:  *createdAfter,
+			CreatedBefore: *createdBefore,
+			Limit:         *limit,
+		}, *dir, exportFormat)
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return err
+	}
+
+	if len(positional) > 1 {
+		return fmt.Errorf("expected a single task ID, got %d arguments", len(positional))
+	}
+
+	detail, err := client.GetTask(positional[0])
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return export.Write(out, export.NewArchive(detail), exportFormat)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	manusai "github.com/tigusigalpa/manus-ai-go"
+)
+
+func runWebhook(sub string, args []string) error {
+	switch sub {
+	case "simulate":
+		return runWebhookSimulate(args)
+	case "relay":
+		return runWebhookRelay(args)
+	default:
+		return fmt.Errorf("unknown webhook subcommand %q", sub)
+	}
+}
+
+func runWebhookSimulate(args []string) error {
+	fs := flag.NewFlagSet("webhook simulate", flag.ContinueOnError)
+	target := fs.String("url", "http://localhost:8080/webhook", "endpoint to deliver events to")
+	event := fs.String("event", manusai.WebhookEventTaskStopped, "event type: task_created or task_stopped")
+	stopReason := fs.String("stop-reason", manusai.StopReasonFinish, "stop reason for task_stopped: finish or ask")
+	taskID := fs.String("task-id", "task_simulated", "task ID to put in the payload")
+	title := fs.String("title", "Simulated task", "task title for task_created")
+	message := fs.String("message", "Simulated task finished.", "message for task_stopped")
+	fromTask := fs.String("from-task", "", "build the payload from a real task (requires MANUS_AI_API_KEY)")
+	script := fs.String("script", "", "JSON file with a sequence of {delay, event} steps")
+	signKey := fs.String("sign-key", "", "PEM RSA private key used to sign deliveries")
+	signURL := fs.String("sign-url", "", "URL to include in the signature (default --url)")
+	var attachments stringList
+	fs.Var(&attachments, "attach", "attachment as name=url[@size] (repeatable)")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	opts := &manusai.SimulateOptions{SignURL: *signURL}
+	if *signKey != "" {
+		keyPEM, err := os.ReadFile(*signKey)
+		if err != nil {
+			return err
+		}
+		signer, err := manusai.NewWebhookSigner(keyPEM)
+		if err != nil {
+			return err
+		}
+		opts.Signer = signer
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			return err
+		}
+		steps, err := manusai.ParseSimulationScript(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if err := manusai.SimulateWebhookSequence(ctx, *target, steps, opts); err != nil {
+			return err
+		}
+		fmt.Printf("Delivered %d events to %s\n", len(steps), *target)
+		return nil
+	}
+
+	var payload *manusai.WebhookPayload
+	switch {
+	case *fromTask != "":
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		detail, err := client.GetTask(*fromTask)
+		if err != nil {
+			return err
+		}
+		if *event == manusai.WebhookEventTaskCreated {
+			payload = manusai.NewTaskCreatedEvent(detail.ID, detail.Title, "")
+		} else {
+			payload = manusai.NewTaskStoppedEventFromDetail(detail, *stopReason)
+		}
+	case *event == manusai.WebhookEventTaskCreated:
+		payload = manusai.NewTaskCreatedEvent(*taskID, *title, "")
+	case *event == manusai.WebhookEventTaskStopped:
+		atts, err := parseAttachments(attachments)
+		if err != nil {
+			return err
+		}
+		payload = manusai.NewTaskStoppedEvent(*taskID, *stopReason, *message, atts)
+	default:
+		return fmt.Errorf("unsupported event type %q", *event)
+	}
+
+	if err := manusai.SimulateWebhookWithOptions(ctx, *target, payload, opts); err != nil {
+		return err
+	}
+	fmt.Printf("Delivered %s (%s) to %s\n", payload.EventType, payload.EventID, *target)
+	return nil
+}
+
+func parseAttachments(values []string) ([]manusai.OutputAttachment, error) {
+	var result []manusai.OutputAttachment
+	for _, value := range values {
+		name, rest, ok := strings.Cut(value, "=")
+		if !ok || name == "" || rest == "" {
+			return nil, fmt.Errorf("invalid attachment %q, expected name=url[@size]", value)
+		}
+
+		att := manusai.OutputAttachment{FileName: name, URL: rest}
+		if i := strings.LastIndex(rest, "@"); i > 0 {
+			if size, err := strconv.ParseInt(rest[i+1:], 10, 64); err == nil {
+				att.URL = rest[:i]
+				att.SizeBytes = size
+			}
+		}
+		result = append(result, att)
+	}
+	return result, nil
+}
+
+// relayConfigFile is the JSON layout accepted by "manus webhook relay --config".
+type relayConfigFile struct {
+	Destinations []struct {
+		Name        string            `json:"name"`
+		URL         string            `json:"url"`
+		Events      []string          `json:"events"`
+		Tags        []string          `json:"tags"`
+		Headers     map[string]string `json:"headers"`
+		QueueSize   int               `json:"queue_size"`
+		MaxAttempts int               `json:"max_attempts"`
+		Backoff     string            `json:"backoff"`
+		MaxBackoff  string            `json:"max_backoff"`
+	} `json:"destinations"`
+}
+
+func runWebhookRelay(args []string) error {
+	fs := flag.NewFlagSet("webhook relay", flag.ContinueOnError)
+	listen := fs.String("listen", ":8080", "address to listen on")
+	path := fs.String("path", "/webhook", "path that receives Manus webhooks")
+	metricsPath := fs.String("metrics-path", "/metrics", "path serving per-destination delivery metrics as JSON")
+	configPath := fs.String("config", "", "JSON file listing destinations with event and tag filters")
+	verifyKey := fs.String("verify-key", "", "PEM RSA public key used to verify incoming webhooks")
+	fetchKey := fs.Bool("fetch-key", false, "fetch the verification key from the API (requires MANUS_AI_API_KEY)")
+	verifyURL := fs.String("verify-url", "", "public webhook URL included in signatures")
+	taskStore := fs.String("task-store", "", "task store file used to resolve tag filters, re-read when it changes")
+	var dests stringList
+	fs.Var(&dests, "dest", "destination as [name=]url receiving every event (repeatable)")
+
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	var config manusai.WebhookRelayConfig
+	if *configPath != "" {
+		destinations, err := loadRelayConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		config.Destinations = destinations
+	}
+	for _, value := range dests {
+		dest := manusai.RelayDestination{URL: value}
+		if name, url, ok := strings.Cut(value, "="); ok && !strings.Contains(name, "://") {
+			dest.Name, dest.URL = name, url
+		}
+		config.Destinations = append(config.Destinations, dest)
+	}
+
+	var keyPEM []byte
+	switch {
+	case *verifyKey != "":
+		data, err := os.ReadFile(*verifyKey)
+		if err != nil {
+			return err
+		}
+		keyPEM = data
+	case *fetchKey:
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		key, err := client.GetWebhookPublicKey()
+		if err != nil {
+			return err
+		}
+		keyPEM = []byte(key)
+	}
+	if keyPEM != nil {
+		verifier, err := manusai.NewRSAWebhookVerifier(keyPEM, *verifyURL)
+		if err != nil {
+			return err
+		}
+		config.Verifier = verifier
+	} else {
+		fmt.Fprintln(os.Stderr, "manus: warning: incoming webhooks are not verified (use --verify-key or --fetch-key)")
+	}
+
+	if *taskStore != "" {
+		store, err := newReloadingTaskStore(*taskStore)
+		if err != nil {
+			return err
+		}
+		config.TaskStore = store
+	}
+
+	config.OnError = func(destination string, err error) {
+		fmt.Fprintf(os.Stderr, "manus: relay to %s: %v\n", destination, err)
+	}
+
+	relay, err := manusai.NewWebhookRelay(config)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, relay)
+	mux.HandleFunc(*metricsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(relay.Metrics())
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go relay.Start(ctx)
+
+	fmt.Printf("Relaying %s%s to %d destinations\n", *listen, *path, len(config.Destinations))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// reloadingTaskStore re-reads a FileTaskStore whenever the file changes on
+// disk, so tasks created by other processes after the relay started are
+// still matched by tag filters.
+type reloadingTaskStore struct {
+	path string
+
+	mu      sync.Mutex
+	store   *manusai.FileTaskStore
+	modTime time.Time
+	size    int64
+}
+
+func newReloadingTaskStore(path string) (*reloadingTaskStore, error) {
+	s := &reloadingTaskStore{path: path}
+	if _, err := s.current(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// current returns the store, reloading it if the file's modification time
+// or size has changed. A file that cannot be read keeps the last copy.
+func (s *reloadingTaskStore) current() (*manusai.FileTaskStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		if s.store != nil {
+			return s.store, nil
+		}
+		return nil, err
+	}
+
+	var modTime time.Time
+	var size int64
+	if info != nil {
+		modTime, size = info.ModTime(), info.Size()
+	}
+	if s.store != nil && modTime.Equal(s.modTime) && size == s.size {
+		return s.store, nil
+	}
+
+	store, err := manusai.NewFileTaskStore(s.path)
+	if err != nil {
+		if s.store != nil {
+			return s.store, nil
+		}
+		return nil, err
+	}
+	s.store, s.modTime, s.size = store, modTime, size
+	return store, nil
+}
+
+func (s *reloadingTaskStore) Put(record *manusai.TaskRecord) error {
+	store, err := s.current()
+	if err != nil {
+		return err
+	}
+	return store.Put(record)
+}
+
+func (s *reloadingTaskStore) Get(taskID string) (*manusai.TaskRecord, error) {
+	store, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(taskID)
+}
+
+func (s *reloadingTaskStore) Update(taskID string, fn func(record *manusai.TaskRecord)) error {
+	store, err := s.current()
+	if err != nil {
+		return err
+	}
+	return store.Update(taskID, fn)
+}
+
+func (s *reloadingTaskStore) Delete(taskID string) error {
+	store, err := s.current()
+	if err != nil {
+		return err
+	}
+	return store.Delete(taskID)
+}
+
+func (s *reloadingTaskStore) Find(query manusai.TaskQuery) ([]*manusai.TaskRecord, error) {
+	store, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return store.Find(query)
+}
+
+func loadRelayConfig(path string) ([]manusai.RelayDestination, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file relayConfigFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid relay config %s: %w", path, err)
+	}
+
+	destinations := make([]manusai.RelayDestination, len(file.Destinations))
+	for i, d := range file.Destinations {
+		dest := manusai.RelayDestination{
+			Name:        d.Name,
+			URL:         d.URL,
+			Events:      d.Events,
+			Tags:        d.Tags,
+			Headers:     d.Headers,
+			QueueSize:   d.QueueSize,
+			MaxAttempts: d.MaxAttempts,
+		}
+		if d.Backoff != "" {
+			if dest.Backoff, err = time.ParseDuration(d.Backoff); err != nil {
+				return nil, fmt.Errorf("relay destination %d: %w", i+1, err)
+			}
+		}
+		if d.MaxBackoff != "" {
+			if dest.MaxBackoff, err = time.ParseDuration(d.MaxBackoff); err != nil {
+				return nil, fmt.Errorf("relay destination %d: %w", i+1, err)
+			}
+		}
+		destinations[i] = dest
+	}
+	return destinations, nil
+}
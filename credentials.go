@@ -0,0 +1,144 @@
+package manusai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redactedKey = "[REDACTED]"
+
+// CredentialsProvider supplies the API key for each request. It is consulted
+// on every call, so a rotated key is picked up without recreating the client.
+type CredentialsProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// CredentialsRefresher is implemented by providers that cache the key. The
+// client calls Refresh after a 401 before retrying the request once.
+type CredentialsRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+type CredentialsFunc func(ctx context.Context) (string, error)
+
+func (f CredentialsFunc) APIKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// WithCredentials replaces the key passed to NewClient, which may then be
+// empty.
+func WithCredentials(provider CredentialsProvider) ClientOption {
+	return func(c *Client) {
+		c.credentials = provider
+	}
+}
+
+type staticCredentials string
+
+func StaticCredentials(apiKey string) CredentialsProvider {
+	return staticCredentials(apiKey)
+}
+
+func (s staticCredentials) APIKey(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+func (s staticCredentials) String() string {
+	return redactedKey
+}
+
+type envCredentials string
+
+// EnvCredentials reads the key from the named environment variable on every
+// request.
+func EnvCredentials(name string) CredentialsProvider {
+	return envCredentials(name)
+}
+
+func (e envCredentials) APIKey(ctx context.Context) (string, error) {
+	key := strings.TrimSpace(os.Getenv(string(e)))
+	if key == "" {
+		return "", &AuthenticationError{Message: fmt.Sprintf("environment variable %s is empty", string(e))}
+	}
+	return key, nil
+}
+
+// FileCredentials reads the key from a file, such as one mounted by a secret
+// manager. The file is re-read whenever its modification time or size
+// changes, and on Refresh.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+}
+
+func NewFileCredentials(path string) (*FileCredentials, error) {
+	f := &FileCredentials{path: path}
+	if err := f.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileCredentials) APIKey(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err == nil && (!info.ModTime().Equal(f.modTime) || info.Size() != f.size) {
+		if err := f.loadLocked(); err != nil {
+			return "", err
+		}
+	}
+	if f.key == "" {
+		return "", &AuthenticationError{Message: fmt.Sprintf("credentials file %s is empty", f.path)}
+	}
+	return f.key, nil
+}
+
+func (f *FileCredentials) Refresh(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.loadLocked()
+}
+
+func (f *FileCredentials) String() string {
+	return "FileCredentials(" + f.path + ")"
+}
+
+func (f *FileCredentials) loadLocked() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return &AuthenticationError{Message: fmt.Sprintf("failed to read credentials file: %v", err), Err: err}
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return &AuthenticationError{Message: fmt.Sprintf("failed to read credentials file: %v", err), Err: err}
+	}
+
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return &AuthenticationError{Message: fmt.Sprintf("credentials file %s is empty", f.path)}
+	}
+
+	f.key = key
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return nil
+}
+
+// redactKey removes the API key from text that ends up in error messages,
+// such as response bodies that echo the request.
+func redactKey(text, key string) string {
+	if key == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, key, redactedKey)
+}
@@ -0,0 +1,100 @@
+package manusai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCredentialsRotation(t *testing.T) {
+	var current atomic.Value
+	current.Store("key-old")
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != current.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, `{"error":"invalid key %s"}`, r.Header.Get("Authorization"))
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(path, []byte("key-old\n"), 0600))
+
+	creds, err := NewFileCredentials(path)
+	require.NoError(t, err)
+	client, err := NewClient("", WithCredentials(creds), WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	_, err = client.ListFiles()
+	require.NoError(t, err)
+
+	// Rotate on the server and on disk; the 401 triggers a re-read and retry.
+	current.Store("key-new")
+	require.NoError(t, os.WriteFile(path, []byte("key-new\n"), 0600))
+	atomic.StoreInt32(&requests, 0)
+
+	_, err = client.ListFiles()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, atomic.LoadInt32(&requests), int32(2))
+
+	// A revoked key fails after a single retry without leaking the key.
+	current.Store("key-other")
+	atomic.StoreInt32(&requests, 0)
+	_, err = client.ListFiles()
+	require.Error(t, err)
+	assert.IsType(t, &AuthenticationError{}, err)
+	assert.NotContains(t, err.Error(), "key-new")
+	assert.Contains(t, err.Error(), redactedKey)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestCredentialsFuncRetriesWithNewKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"task_id":"task_1"}`))
+	}))
+	defer server.Close()
+
+	var calls int32
+	provider := CredentialsFunc(func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return "stale", nil
+		}
+		return "fresh", nil
+	})
+
+	client, err := NewClient("", WithCredentials(provider), WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	result, err := client.CreateTask("Test prompt", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "task_1", result.TaskID)
+}
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("MANUS_TEST_KEY", " env-key \n")
+	key, err := EnvCredentials("MANUS_TEST_KEY").APIKey(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "env-key", key)
+
+	t.Setenv("MANUS_TEST_KEY", "")
+	_, err = EnvCredentials("MANUS_TEST_KEY").APIKey(context.Background())
+	assert.IsType(t, &AuthenticationError{}, err)
+
+	assert.Equal(t, redactedKey, fmt.Sprint(StaticCredentials("secret")))
+}
@@ -0,0 +1,153 @@
+package manusai
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type DeprecationMode int
+
+const (
+	// DeprecationWarn logs deprecated profiles and sends them unchanged.
+	DeprecationWarn DeprecationMode = iota
+	// DeprecationMigrate sends the replacement profile instead.
+	DeprecationMigrate
+	// DeprecationReject fails CreateTask with a ValidationError.
+	DeprecationReject
+)
+
+func (m DeprecationMode) String() string {
+	switch m {
+	case DeprecationWarn:
+		return "warn"
+	case DeprecationMigrate:
+		return "migrate"
+	case DeprecationReject:
+		return "reject"
+	default:
+		return fmt.Sprintf("DeprecationMode(%d)", int(m))
+	}
+}
+
+// DeprecationPolicy decides what CreateTask does with deprecated and unknown
+// agent profiles, as listed by the client's AgentProfileRegistry. Unknown
+// profiles are logged, or rejected in DeprecationReject mode.
+type DeprecationPolicy struct {
+	Mode DeprecationMode
+	// Replacements maps deprecated profiles to the profile used by
+	// DeprecationMigrate, overriding the registry's replacement.
+	Replacements map[string]string
+	// OnDeprecated is called for every task created with a deprecated
+	// profile, to help find call sites that still need updating.
+	OnDeprecated func(DeprecationReport)
+}
+
+type DeprecationReport struct {
+	Profile string
+	// Replacement is empty if no replacement is known.
+	Replacement string
+	// Action is the mode actually applied; DeprecationMigrate falls back to
+	// DeprecationWarn when there is no replacement.
+	Action DeprecationMode
+	// Caller is the file:line of the CreateTask call.
+	Caller string
+	Time   time.Time
+}
+
+func WithDeprecationPolicy(policy DeprecationPolicy) ClientOption {
+	return func(c *Client) {
+		c.deprecation = policy
+	}
+}
+
+// WithLogger sets the logger for client warnings. The default is
+// slog.Default(); nil discards them.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) {
+		if logger == nil {
+			logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		}
+		c.logger = logger
+	}
+}
+
+// resolveAgentProfile applies the deprecation policy and returns the profile
+// to send. It reads the registry's cached list and never contacts the
+// source, so creating a task makes no extra requests.
+func (c *Client) resolveAgentProfile(profile string) (string, error) {
+	info, known := c.profiles.Lookup(profile)
+	policy := c.deprecation
+
+	if !known {
+		if policy.Mode == DeprecationReject {
+			return "", &ValidationError{Message: fmt.Sprintf("Unknown agent profile %q", profile)}
+		}
+		c.logger.Warn("manus: unknown agent profile", "profile", profile)
+		return profile, nil
+	}
+	if !info.Deprecated {
+		return profile, nil
+	}
+
+	report := DeprecationReport{
+		Profile:     profile,
+		Replacement: info.Replacement,
+		Action:      policy.Mode,
+		Caller:      externalCaller(),
+		Time:        time.Now(),
+	}
+	if replacement, ok := policy.Replacements[profile]; ok {
+		report.Replacement = replacement
+	}
+	if report.Action == DeprecationMigrate && report.Replacement == "" {
+		report.Action = DeprecationWarn
+	}
+	if policy.OnDeprecated != nil {
+		policy.OnDeprecated(report)
+	}
+
+	switch report.Action {
+	case DeprecationReject:
+		msg := fmt.Sprintf("Agent profile %q is deprecated", profile)
+		if report.Replacement != "" {
+			msg += fmt.Sprintf("; use %q instead", report.Replacement)
+		}
+		return "", &ValidationError{Message: msg}
+	case DeprecationMigrate:
+		c.logger.Info("manus: migrated deprecated agent profile", "profile", profile, "replacement", report.Replacement, "caller", report.Caller)
+		return report.Replacement, nil
+	default:
+		c.logger.Warn("manus: deprecated agent profile", "profile", profile, "replacement", report.Replacement, "caller", report.Caller)
+		return profile, nil
+	}
+}
+
+// externalCaller returns the file:line of the first caller outside this
+// package.
+func externalCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		inPackage := strings.HasPrefix(frame.Function, packagePrefix) && !strings.HasSuffix(frame.File, "_test.go")
+		if !inPackage {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+var packagePrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+	return name[:slash+1+strings.Index(name[slash+1:], ".")+1]
+}()
@@ -0,0 +1,130 @@
+package manusai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProfileServer(t *testing.T, sent *[]string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/agent-profiles" {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			return
+		}
+		var body struct {
+			AgentProfile string `json:"agentProfile"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		*sent = append(*sent, body.AgentProfile)
+		w.Write([]byte(`{"task_id":"task_1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestPackagePrefix(t *testing.T) {
+	assert.Equal(t, "github.com/tigusigalpa/manus-ai-go.", packagePrefix)
+}
+
+func TestDeprecationWarn(t *testing.T) {
+	var sent []string
+	var logs bytes.Buffer
+	var reports []DeprecationReport
+	client, err := NewClient("test-key", WithBaseURL(newProfileServer(t, &sent)),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithDeprecationPolicy(DeprecationPolicy{OnDeprecated: func(r DeprecationReport) { reports = append(reports, r) }}))
+	require.NoError(t, err)
+
+	_, err = client.CreateTask("Go", &TaskOptions{AgentProfile: AgentProfileSpeed})
+	require.NoError(t, err)
+	assert.Equal(t, []string{AgentProfileSpeed}, sent)
+	assert.Contains(t, logs.String(), "deprecated agent profile")
+	assert.Contains(t, logs.String(), "replacement=manus-1.6-lite")
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, DeprecationWarn, reports[0].Action)
+	assert.Contains(t, reports[0].Caller, "deprecation_test.go:")
+
+	logs.Reset()
+	_, err = client.CreateTask("Go", &TaskOptions{AgentProfile: "manus-9"})
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "unknown agent profile")
+	assert.Len(t, reports, 1)
+}
+
+func TestDeprecationMigrate(t *testing.T) {
+	var sent []string
+	var reports []DeprecationReport
+	client, err := NewClient("test-key", WithBaseURL(newProfileServer(t, &sent)), WithLogger(nil),
+		WithDeprecationPolicy(DeprecationPolicy{
+			Mode:         DeprecationMigrate,
+			Replacements: map[string]string{AgentProfileQuality: AgentProfileManus16Max},
+			OnDeprecated: func(r DeprecationReport) { reports = append(reports, r) },
+		}))
+	require.NoError(t, err)
+
+	for _, profile := range []string{AgentProfileSpeed, AgentProfileQuality, AgentProfileManus16} {
+		_, err = client.CreateTask("Go", &TaskOptions{AgentProfile: profile})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{AgentProfileManus16Lite, AgentProfileManus16Max, AgentProfileManus16}, sent)
+	require.Len(t, reports, 2)
+	assert.Equal(t, DeprecationMigrate, reports[1].Action)
+	assert.Equal(t, AgentProfileManus16Max, reports[1].Replacement)
+}
+
+func TestDeprecationReject(t *testing.T) {
+	var sent []string
+	client, err := NewClient("test-key", WithBaseURL(newProfileServer(t, &sent)),
+		WithDeprecationPolicy(DeprecationPolicy{Mode: DeprecationReject}))
+	require.NoError(t, err)
+
+	_, err = client.CreateTask("Go", &TaskOptions{AgentProfile: AgentProfileSpeed})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Contains(t, err.Error(), `use "manus-1.6-lite" instead`)
+
+	_, err = client.CreateTask("Go", &TaskOptions{AgentProfile: "manus-9"})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Contains(t, err.Error(), "Unknown agent profile")
+	assert.Empty(t, sent)
+}
+
+func TestDeprecationUsesCachedProfiles(t *testing.T) {
+	var posts, profileRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/agent-profiles" {
+			profileRequests++
+			w.Write([]byte(`{"data":[{"name":"manus-2.0"},{"name":"manus-1.6"}]}`))
+			return
+		}
+		posts++
+		w.Write([]byte(`{"task_id":"task_1"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithAgentProfilesFromAPI(0),
+		WithDeprecationPolicy(DeprecationPolicy{Mode: DeprecationReject}))
+	require.NoError(t, err)
+
+	// CreateTask only reads the cached list, which is still the built-in one.
+	_, err = client.CreateTask("Go", &TaskOptions{AgentProfile: "manus-2.0"})
+	require.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, 0, profileRequests)
+
+	client.ListAgentProfiles(context.Background())
+	assert.Equal(t, 1, profileRequests)
+
+	resp, err := client.CreateTask("Go", &TaskOptions{AgentProfile: "manus-2.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "manus-2.0", resp.AgentProfile)
+	assert.Equal(t, 1, posts)
+	assert.Equal(t, 1, profileRequests)
+}
@@ -0,0 +1,313 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const DefaultDownloadConcurrency = 4
+
+type DownloadOptions struct {
+	Concurrency  int
+	MaxSizeBytes int64
+}
+
+func (c *Client) DownloadAttachments(ctx context.Context, source interface{}, destDir string) ([]string, error) {
+	return c.DownloadAttachmentsWithOptions(ctx, source, destDir, nil)
+}
+
+func (c *Client) DownloadAttachmentsWithOptions(ctx context.Context, source interface{}, destDir string, options *DownloadOptions) ([]string, error) {
+	if strings.TrimSpace(destDir) == "" {
+		return nil, &ValidationError{Message: "Destination directory cannot be empty"}
+	}
+
+	attachments, err := outputAttachmentsFrom(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, &ManusAIError{Message: fmt.Sprintf("Failed to create destination directory: %v", err), Err: err}
+	}
+
+	concurrency := DefaultDownloadConcurrency
+	var maxSize int64
+	if options != nil {
+		if options.Concurrency > 0 {
+			concurrency = options.Concurrency
+		}
+		maxSize = options.MaxSizeBytes
+	}
+
+	names := uniqueFileNames(attachments)
+	paths := make([]string, len(attachments))
+	errs := make([]error, len(attachments))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, att := range attachments {
+		wg.Add(1)
+		go func(i int, att OutputAttachment) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			path := filepath.Join(destDir, names[i])
+			if err := c.downloadAttachment(ctx, att, path, maxSize); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", att.FileName, err)
+				return
+			}
+			paths[i] = path
+		}(i, att)
+	}
+	wg.Wait()
+
+	return paths, errors.Join(errs...)
+}
+
+func (c *Client) OpenAttachment(ctx context.Context, attachment OutputAttachment) (io.ReadCloser, error) {
+	resp, err := c.getAttachment(ctx, attachment, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment.SizeBytes > 0 {
+		return &sizeCheckedReader{body: resp.Body, expected: attachment.SizeBytes}, nil
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) downloadAttachment(ctx context.Context, att OutputAttachment, path string, maxSize int64) error {
+	if maxSize > 0 && att.SizeBytes > maxSize {
+		return &ValidationError{Message: fmt.Sprintf("Attachment size %d exceeds limit of %d bytes", att.SizeBytes, maxSize)}
+	}
+
+	if info, err := os.Stat(path); err == nil && att.SizeBytes > 0 && info.Size() == att.SizeBytes {
+		return nil
+	}
+
+	partPath := path + ".part"
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+		if att.SizeBytes > 0 && offset > att.SizeBytes {
+			offset = 0
+		}
+	}
+
+	resp, err := c.getAttachment(ctx, att, offset)
+	if err != nil {
+		return err
+	}
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent && !rangeStartsAt(resp.Header.Get("Content-Range"), offset) {
+		// The server answered a different range; start over rather than
+		// append bytes at the wrong position.
+		resp.Body.Close()
+		offset = 0
+		if resp, err = c.getAttachment(ctx, att, 0); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+		offset = 0
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return &ManusAIError{Message: fmt.Sprintf("Failed to open %s: %v", partPath, err), Err: err}
+	}
+
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize-offset+1)
+	}
+
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &ManusAIError{Message: fmt.Sprintf("Failed to download attachment: %v", err), Err: err}
+	}
+
+	total := offset + written
+	if maxSize > 0 && total > maxSize {
+		os.Remove(partPath)
+		return &ValidationError{Message: fmt.Sprintf("Attachment exceeds limit of %d bytes", maxSize)}
+	}
+	if att.SizeBytes > 0 && total != att.SizeBytes {
+		if total > att.SizeBytes {
+			os.Remove(partPath)
+		}
+		return &ManusAIError{Message: fmt.Sprintf("Attachment size mismatch: expected %d bytes, got %d", att.SizeBytes, total)}
+	}
+
+	if err := os.Rename(partPath, path); err != nil {
+		return &ManusAIError{Message: fmt.Sprintf("Failed to finalize %s: %v", path, err), Err: err}
+	}
+	return nil
+}
+
+// rangeStartsAt reports whether a Content-Range header of the form
+// "bytes start-end/total" starts at offset.
+func rangeStartsAt(contentRange string, offset int64) bool {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(contentRange), "bytes ")
+	if !ok {
+		return false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	return err == nil && n == offset
+}
+
+func (c *Client) getAttachment(ctx context.Context, att OutputAttachment, offset int64) (*http.Response, error) {
+	if strings.TrimSpace(att.URL) == "" {
+		return nil, &ValidationError{Message: "Attachment URL cannot be empty"}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", att.URL, nil)
+	if err != nil {
+		return nil, &ManusAIError{Message: fmt.Sprintf("Failed to create download request: %v", err), Err: err}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := c.downloadClient().Do(req)
+	if err != nil {
+		return nil, &ManusAIError{Message: fmt.Sprintf("Download failed: %v", err), Err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, &ManusAIError{
+			Message:    fmt.Sprintf("Download failed with status %d: %s", resp.StatusCode, string(body)),
+			StatusCode: resp.StatusCode,
+		}
+	}
+
+	return resp, nil
+}
+
+// downloadClient shares the API client's transport but drops its overall
+// timeout; large downloads are bounded by the caller's context instead.
+func (c *Client) downloadClient() *http.Client {
+	return &http.Client{
+		Transport:     c.httpClient.Transport,
+		CheckRedirect: c.httpClient.CheckRedirect,
+		Jar:           c.httpClient.Jar,
+	}
+}
+
+func outputAttachmentsFrom(source interface{}) ([]OutputAttachment, error) {
+	switch v := source.(type) {
+	case *TaskDetail:
+		if v == nil {
+			return nil, &ValidationError{Message: "Task detail cannot be nil"}
+		}
+		return v.Attachments, nil
+	case TaskDetail:
+		return v.Attachments, nil
+	case *WebhookPayload:
+		if v == nil {
+			return nil, &ValidationError{Message: "Webhook payload cannot be nil"}
+		}
+		return GetOutputAttachments(v), nil
+	case []OutputAttachment:
+		return v, nil
+	case OutputAttachment:
+		return []OutputAttachment{v}, nil
+	default:
+		return nil, &ValidationError{Message: fmt.Sprintf("Unsupported attachment source type %T", source)}
+	}
+}
+
+// SanitizeFileName reduces an attachment name to a single safe path element
+// so that server-provided names cannot escape the destination directory.
+func SanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(filepath.Clean("/" + name))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == ':' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+
+	if name == "" || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+func uniqueFileNames(attachments []OutputAttachment) []string {
+	names := make([]string, len(attachments))
+	seen := make(map[string]int)
+	for i, att := range attachments {
+		name := SanitizeFileName(att.FileName)
+		if n := seen[name]; n > 0 {
+			// Skip suffixes that another attachment already uses, and
+			// reserve the generated name so a later one cannot take it.
+			ext := filepath.Ext(name)
+			base := strings.TrimSuffix(name, ext)
+			candidate := fmt.Sprintf("%s-%d%s", base, n, ext)
+			for seen[candidate] > 0 {
+				n++
+				candidate = fmt.Sprintf("%s-%d%s", base, n, ext)
+			}
+			seen[name] = n + 1
+			name = candidate
+		}
+		seen[name] = 1
+		names[i] = name
+	}
+	return names
+}
+
+type sizeCheckedReader struct {
+	body     io.ReadCloser
+	expected int64
+	read     int64
+}
+
+func (r *sizeCheckedReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.read += int64(n)
+	if r.read > r.expected {
+		return n, fmt.Errorf("attachment larger than expected %d bytes", r.expected)
+	}
+	if err == io.EOF && r.read != r.expected {
+		return n, fmt.Errorf("attachment size mismatch: expected %d bytes, got %d", r.expected, r.read)
+	}
+	return n, err
+}
+
+func (r *sizeCheckedReader) Close() error {
+	return r.body.Close()
+}
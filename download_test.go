@@ -0,0 +1,164 @@
+package manusai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{"..\\..\\windows\\system.ini", "system.ini"},
+		{"/absolute/path.txt", "path.txt"},
+		{"..", "attachment"},
+		{"", "attachment"},
+		{".hidden", "hidden"},
+		{"bad\x00name.txt", "badname.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SanitizeFileName(tt.name))
+		})
+	}
+}
+
+func TestDownloadAttachments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(r.URL.Path))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key")
+	dir := t.TempDir()
+
+	payload := &WebhookPayload{
+		EventType: "task_stopped",
+		TaskDetail: map[string]interface{}{
+			"attachments": []interface{}{
+				map[string]interface{}{"file_name": "../a.txt", "url": server.URL + "/aaaa", "size_bytes": float64(5)},
+				map[string]interface{}{"file_name": "a.txt", "url": server.URL + "/bbbbbb", "size_bytes": float64(7)},
+			},
+		},
+	}
+
+	paths, err := client.DownloadAttachments(context.Background(), payload, dir)
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	assert.Equal(t, filepath.Join(dir, "a.txt"), paths[0])
+	assert.Equal(t, filepath.Join(dir, "a-1.txt"), paths[1])
+
+	content, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	assert.Equal(t, "/bbbbbb", string(content))
+}
+
+func TestDownloadAttachmentsResume(t *testing.T) {
+	var rangeHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("hello world"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt.part"), []byte("hello"), 0o644))
+
+	detail := &TaskDetail{Attachments: []OutputAttachment{{FileName: "out.txt", URL: server.URL, SizeBytes: 11}}}
+	paths, err := client.DownloadAttachments(context.Background(), detail, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "bytes=5-", rangeHeader)
+
+	content, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(content))
+}
+
+func TestDownloadAttachmentsResumeWrongRange(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			// Ignore the requested offset and send the file from the start.
+			w.Header().Set("Content-Range", "bytes 0-10/11")
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt.part"), []byte("hello"), 0o644))
+
+	detail := &TaskDetail{Attachments: []OutputAttachment{{FileName: "out.txt", URL: server.URL, SizeBytes: 11}}}
+	paths, err := client.DownloadAttachments(context.Background(), detail, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bytes=5-", ""}, requests)
+
+	content, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(content))
+}
+
+func TestUniqueFileNames(t *testing.T) {
+	names := uniqueFileNames([]OutputAttachment{
+		{FileName: "a.txt"}, {FileName: "a.txt"}, {FileName: "a-1.txt"}, {FileName: "a.txt"},
+	})
+	assert.Equal(t, []string{"a.txt", "a-1.txt", "a-1-1.txt", "a-2.txt"}, names)
+}
+
+func TestDownloadAttachmentsSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key")
+	dir := t.TempDir()
+
+	_, err := client.DownloadAttachmentsWithOptions(context.Background(),
+		[]OutputAttachment{{FileName: "big.bin", URL: server.URL}}, dir, &DownloadOptions{MaxSizeBytes: 4})
+	assert.Error(t, err)
+	_, statErr := os.Stat(filepath.Join(dir, "big.bin"))
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestOpenAttachment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("streamed"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key")
+
+	rc, err := client.OpenAttachment(context.Background(), OutputAttachment{URL: server.URL, SizeBytes: 8})
+	require.NoError(t, err)
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", string(content))
+
+	rc, err = client.OpenAttachment(context.Background(), OutputAttachment{URL: server.URL, SizeBytes: 3})
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	assert.Error(t, err)
+	rc.Close()
+}
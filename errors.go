@@ -1,6 +1,9 @@
 package manusai
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type ManusAIError struct {
 	Message    string
@@ -52,3 +55,40 @@ func (e *ValidationError) Error() string {
 func (e *ValidationError) Unwrap() error {
 	return e.Err
 }
+
+type BudgetExceededError struct {
+	Scope string
+	Tag   string
+	Spent float64
+	Limit float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Tag != "" {
+		return fmt.Sprintf("budget exceeded (%s %s): spent %.2f of %.2f credits", e.Scope, e.Tag, e.Spent, e.Limit)
+	}
+	return fmt.Sprintf("budget exceeded (%s): spent %.2f of %.2f credits", e.Scope, e.Spent, e.Limit)
+}
+
+type CircuitOpenError struct {
+	Endpoint   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("circuit open for %s: retry after %s", e.Endpoint, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("circuit open for %s: waiting for probe requests", e.Endpoint)
+}
+
+// FileStatusError reports a file that reached a terminal status other than
+// ready.
+type FileStatusError struct {
+	FileID string
+	Status FileStatus
+}
+
+func (e *FileStatusError) Error() string {
+	return fmt.Sprintf("file %s is %s", e.FileID, e.Status)
+}
@@ -0,0 +1,464 @@
+package manusai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultEventPollInterval = 10 * time.Second
+
+	// maxFinishedTasks bounds how many stopped tasks are remembered so that
+	// late deliveries for them are ignored.
+	maxFinishedTasks = 10000
+)
+
+type TaskEventType string
+
+const (
+	TaskEventCreated       TaskEventType = "created"
+	TaskEventProgressed    TaskEventType = "progressed"
+	TaskEventMessage       TaskEventType = "message"
+	TaskEventStopped       TaskEventType = "stopped"
+	TaskEventAskedForInput TaskEventType = "asked_for_input"
+)
+
+const (
+	TaskEventSourceWebhook = "webhook"
+	TaskEventSourcePolling = "polling"
+)
+
+type TaskEvent struct {
+	Type       TaskEventType
+	TaskID     string
+	Status     string
+	StopReason string
+	Message    *TaskMessage
+	Source     string
+	Detail     *TaskDetail
+	Payload    *WebhookPayload
+	Time       time.Time
+}
+
+type TaskEventHandler func(event TaskEvent)
+
+type TaskEventsConfig struct {
+	PollInterval time.Duration
+	PollStatuses []string
+	OnError      func(err error)
+}
+
+// TaskEvents turns webhook deliveries and GetTasks/GetTask polling into one
+// stream of typed, de-duplicated events. Events for a single task are
+// delivered in order; handlers for different tasks may run concurrently.
+type TaskEvents struct {
+	client *Client
+	config TaskEventsConfig
+
+	mu            sync.Mutex
+	nextID        int
+	handlers      []subscription
+	tasks         map[string]*taskEventState
+	finished      map[string]bool
+	finishedOrder []string
+}
+
+type subscription struct {
+	id      int
+	taskID  string
+	handler TaskEventHandler
+}
+
+type taskEventState struct {
+	mu         sync.Mutex
+	created    bool
+	status     string
+	stopReason string
+	stopCount  int
+	terminal   bool
+	// messages counts message events. polled is how many entries of
+	// TaskDetail.Output have been seen; webhook messages are matched against
+	// them so a message reported by both sources is delivered once.
+	messages      int
+	polled        int
+	pollTail      *TaskMessage
+	unmatched     []TaskMessage
+	webhookEvents map[string]bool
+
+	// queue holds events not yet passed to handlers; dispatching is set
+	// while one goroutine is delivering them.
+	queue       []TaskEvent
+	dispatching bool
+}
+
+func NewTaskEvents(client *Client, config *TaskEventsConfig) *TaskEvents {
+	cfg := TaskEventsConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultEventPollInterval
+	}
+	if len(cfg.PollStatuses) == 0 {
+		cfg.PollStatuses = []string{TaskStatusPending, TaskStatusRunning}
+	}
+
+	return &TaskEvents{
+		client:   client,
+		config:   cfg,
+		tasks:    make(map[string]*taskEventState),
+		finished: make(map[string]bool),
+	}
+}
+
+func (e *TaskEvents) Subscribe(handler TaskEventHandler) func() {
+	return e.SubscribeTask("", handler)
+}
+
+// SubscribeTask registers a handler for a single task. An empty taskID
+// subscribes to all tasks. The returned function removes the subscription.
+func (e *TaskEvents) SubscribeTask(taskID string, handler TaskEventHandler) func() {
+	e.mu.Lock()
+	id := e.nextID
+	e.nextID++
+	e.handlers = append(e.handlers, subscription{id: id, taskID: taskID, handler: handler})
+	e.mu.Unlock()
+
+	if taskID != "" {
+		e.Watch(taskID)
+	}
+
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		for i, sub := range e.handlers {
+			if sub.id == id {
+				e.handlers = append(e.handlers[:i:i], e.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Watch makes the poller track a task even if it does not show up in the
+// GetTasks listing, e.g. because it is hidden from the task list. Tasks that
+// already stopped are not watched again.
+func (e *TaskEvents) Watch(taskID string) {
+	e.state(taskID)
+}
+
+// HandleWebhook converts a webhook payload into task events. The payload is
+// also passed to the client's ObserveWebhook.
+func (e *TaskEvents) HandleWebhook(payload *WebhookPayload) {
+	if payload == nil || payload.TaskDetail == nil {
+		return
+	}
+	if e.client != nil {
+		e.client.ObserveWebhook(payload)
+	}
+
+	taskID, _ := payload.TaskDetail["task_id"].(string)
+	if taskID == "" {
+		return
+	}
+
+	st := e.state(taskID)
+	if st == nil {
+		return
+	}
+	defer e.flush(st)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	key := WebhookDedupeKey(payload)
+	if st.webhookEvents[key] {
+		return
+	}
+	st.webhookEvents[key] = true
+
+	base := TaskEvent{TaskID: taskID, Source: TaskEventSourceWebhook, Payload: payload}
+
+	switch payload.EventType {
+	case WebhookEventTaskCreated:
+		e.markCreated(st, base)
+	case WebhookEventTaskStopped:
+		e.markCreated(st, base)
+		if message, _ := payload.TaskDetail["message"].(string); message != "" {
+			e.markWebhookMessage(st, base, TaskMessage{Role: "assistant", Content: message})
+		}
+		stopReason, _ := payload.TaskDetail["stop_reason"].(string)
+		status := TaskStatusCompleted
+		if stopReason == StopReasonAsk {
+			status = TaskStatusPending
+		}
+		e.markStopped(st, base, status, stopReason)
+	}
+}
+
+// Poll runs a single polling cycle: it lists tasks in the configured
+// statuses, then fetches every listed or previously seen non-terminal task.
+func (e *TaskEvents) Poll() error {
+	return e.poll(context.Background())
+}
+
+func (e *TaskEvents) poll(ctx context.Context) error {
+	if e.client == nil {
+		return &ValidationError{Message: "Task events need a client to poll"}
+	}
+
+	ids, err := e.listActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	for id := range e.tasks {
+		ids[id] = true
+	}
+	e.mu.Unlock()
+
+	var firstErr error
+	for id := range ids {
+		detail, err := e.client.getTask(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		e.handleDetail(detail)
+	}
+
+	return firstErr
+}
+
+// Run polls until ctx is cancelled. Polling errors are reported through
+// TaskEventsConfig.OnError and do not stop the loop.
+func (e *TaskEvents) Run(ctx context.Context) error {
+	if e.client == nil {
+		return &ValidationError{Message: "Task events need a client to poll"}
+	}
+
+	ticker := time.NewTicker(e.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.poll(ctx); err != nil && ctx.Err() == nil && e.config.OnError != nil {
+			e.config.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *TaskEvents) listActive(ctx context.Context) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	filters := &TaskFilters{Status: e.config.PollStatuses}
+
+	for {
+		list, err := e.client.getTasks(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range list.Data {
+			ids[summary.ID] = true
+		}
+		if !list.HasMore || len(list.Data) == 0 {
+			return ids, nil
+		}
+		filters.After = list.Data[len(list.Data)-1].ID
+	}
+}
+
+// handleDetail derives events from a polled task. Polling cannot see stop
+// reasons directly, so a task that returns to pending after running is
+// reported as asking for input.
+func (e *TaskEvents) handleDetail(detail *TaskDetail) {
+	st := e.state(detail.ID)
+	if st == nil {
+		return
+	}
+	defer e.flush(st)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	base := TaskEvent{TaskID: detail.ID, Status: detail.Status, Source: TaskEventSourcePolling, Detail: detail}
+
+	e.markCreated(st, base)
+	for ; st.polled < len(detail.Output); st.polled++ {
+		msg := detail.Output[st.polled]
+		st.pollTail = &msg
+		if st.matchUnmatched(msg) {
+			continue
+		}
+		e.markMessage(st, base, msg)
+	}
+
+	switch {
+	case detail.Status == TaskStatusCompleted || detail.Status == TaskStatusFailed:
+		e.markStopped(st, base, detail.Status, StopReasonFinish)
+	case detail.Status == TaskStatusPending && st.status == TaskStatusRunning:
+		e.markStopped(st, base, detail.Status, StopReasonAsk)
+	default:
+		e.markProgress(st, base, detail.Status)
+	}
+}
+
+func (e *TaskEvents) markCreated(st *taskEventState, base TaskEvent) {
+	if st.created {
+		return
+	}
+	st.created = true
+
+	event := base
+	event.Type = TaskEventCreated
+	if event.Status == "" {
+		event.Status = st.status
+	}
+	st.queue = append(st.queue, event)
+}
+
+// markWebhookMessage reports the message of a task_stopped webhook unless
+// polling already delivered it as the latest output entry.
+func (e *TaskEvents) markWebhookMessage(st *taskEventState, base TaskEvent, msg TaskMessage) {
+	if st.pollTail != nil && *st.pollTail == msg {
+		st.pollTail = nil
+		return
+	}
+	st.unmatched = append(st.unmatched, msg)
+	e.markMessage(st, base, msg)
+}
+
+// matchUnmatched reports whether a polled message was already delivered by
+// a webhook.
+func (st *taskEventState) matchUnmatched(msg TaskMessage) bool {
+	for i, m := range st.unmatched {
+		if m == msg {
+			st.unmatched = append(st.unmatched[:i:i], st.unmatched[i+1:]...)
+			st.pollTail = nil
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TaskEvents) markMessage(st *taskEventState, base TaskEvent, msg TaskMessage) {
+	st.messages++
+
+	event := base
+	event.Type = TaskEventMessage
+	event.Status = st.status
+	event.Message = &msg
+	st.queue = append(st.queue, event)
+}
+
+func (e *TaskEvents) markProgress(st *taskEventState, base TaskEvent, status string) {
+	if st.terminal || status == "" || status == st.status {
+		return
+	}
+	st.status = status
+	st.stopReason = ""
+
+	event := base
+	event.Type = TaskEventProgressed
+	event.Status = status
+	st.queue = append(st.queue, event)
+}
+
+func (e *TaskEvents) markStopped(st *taskEventState, base TaskEvent, status, stopReason string) {
+	if st.terminal || (st.stopReason == stopReason && st.stopCount == st.messages) {
+		return
+	}
+	st.status = status
+	st.stopReason = stopReason
+	st.stopCount = st.messages
+
+	event := base
+	event.Status = status
+	event.StopReason = stopReason
+	if stopReason == StopReasonAsk {
+		event.Type = TaskEventAskedForInput
+	} else {
+		st.terminal = true
+		event.Type = TaskEventStopped
+		e.forget(base.TaskID)
+	}
+	st.queue = append(st.queue, event)
+}
+
+// state returns the state of a task, creating it on first use, or nil if
+// the task has already stopped.
+func (e *TaskEvents) state(taskID string) *taskEventState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.finished[taskID] {
+		return nil
+	}
+	st, ok := e.tasks[taskID]
+	if !ok {
+		st = &taskEventState{webhookEvents: make(map[string]bool)}
+		e.tasks[taskID] = st
+	}
+	return st
+}
+
+// forget drops the state of a stopped task, remembering only its ID.
+func (e *TaskEvents) forget(taskID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.tasks, taskID)
+	e.finished[taskID] = true
+	e.finishedOrder = append(e.finishedOrder, taskID)
+	for len(e.finishedOrder) > maxFinishedTasks {
+		delete(e.finished, e.finishedOrder[0])
+		e.finishedOrder = e.finishedOrder[1:]
+	}
+}
+
+// flush delivers queued events in order without holding st.mu, so handlers
+// may call back into TaskEvents. If another goroutine is already delivering
+// events for the task, it picks up the new ones.
+func (e *TaskEvents) flush(st *taskEventState) {
+	st.mu.Lock()
+	if st.dispatching {
+		st.mu.Unlock()
+		return
+	}
+	st.dispatching = true
+	for len(st.queue) > 0 {
+		event := st.queue[0]
+		st.queue = st.queue[1:]
+		st.mu.Unlock()
+		e.dispatch(event)
+		st.mu.Lock()
+	}
+	st.dispatching = false
+	st.mu.Unlock()
+}
+
+func (e *TaskEvents) dispatch(event TaskEvent) {
+	event.Time = time.Now()
+
+	e.mu.Lock()
+	var handlers []TaskEventHandler
+	for _, sub := range e.handlers {
+		if sub.taskID == "" || sub.taskID == event.TaskID {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
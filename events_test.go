@@ -0,0 +1,202 @@
+package manusai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []TaskEvent
+}
+
+func (r *eventRecorder) handle(event TaskEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) types() []TaskEventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]TaskEventType, len(r.events))
+	for i, e := range r.events {
+		result[i] = e.Type
+	}
+	return result
+}
+
+func TestTaskEventsWebhook(t *testing.T) {
+	events := NewTaskEvents(nil, nil)
+	var all, task1 eventRecorder
+	events.Subscribe(all.handle)
+	unsubscribe := events.SubscribeTask("task_1", task1.handle)
+
+	created := &WebhookPayload{EventType: WebhookEventTaskCreated, TaskDetail: map[string]interface{}{"task_id": "task_1"}}
+	stopped := &WebhookPayload{EventType: WebhookEventTaskStopped, TaskDetail: map[string]interface{}{
+		"task_id": "task_1", "stop_reason": StopReasonFinish, "message": "Done",
+	}}
+
+	events.HandleWebhook(created)
+	events.HandleWebhook(created)
+	events.HandleWebhook(stopped)
+	events.HandleWebhook(stopped)
+	events.HandleWebhook(&WebhookPayload{EventType: WebhookEventTaskCreated, TaskDetail: map[string]interface{}{"task_id": "task_2"}})
+
+	assert.Equal(t, []TaskEventType{TaskEventCreated, TaskEventMessage, TaskEventStopped}, task1.types())
+	assert.Equal(t, []TaskEventType{TaskEventCreated, TaskEventMessage, TaskEventStopped, TaskEventCreated}, all.types())
+
+	unsubscribe()
+	events.HandleWebhook(&WebhookPayload{EventType: WebhookEventTaskCreated, TaskDetail: map[string]interface{}{"task_id": "task_1"}})
+	assert.Len(t, task1.types(), 3)
+}
+
+func TestTaskEventsAskForInput(t *testing.T) {
+	events := NewTaskEvents(nil, nil)
+	var rec eventRecorder
+	events.Subscribe(rec.handle)
+
+	ask := func(message string) *WebhookPayload {
+		return &WebhookPayload{EventType: WebhookEventTaskStopped, TaskDetail: map[string]interface{}{
+			"task_id": "task_1", "stop_reason": StopReasonAsk, "message": message,
+		}}
+	}
+
+	events.HandleWebhook(ask("Which year?"))
+	events.HandleWebhook(ask("Which year?"))
+	events.HandleWebhook(ask("Which region?"))
+
+	assert.Equal(t, []TaskEventType{
+		TaskEventCreated, TaskEventMessage, TaskEventAskedForInput,
+		TaskEventMessage, TaskEventAskedForInput,
+	}, rec.types())
+}
+
+func TestTaskEventsPolling(t *testing.T) {
+	var mu sync.Mutex
+	status := TaskStatusRunning
+	output := `[{"role":"user","content":"Go"}]`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v1/tasks" {
+			assert.ElementsMatch(t, []string{TaskStatusPending, TaskStatusRunning}, r.URL.Query()["status"])
+			if status == TaskStatusRunning {
+				w.Write([]byte(`{"data":[{"id":"task_1","status":"running"}]}`))
+			} else {
+				w.Write([]byte(`{"data":[]}`))
+			}
+			return
+		}
+		fmt.Fprintf(w, `{"id":"task_1","status":%q,"output":%s}`, status, output)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	events := NewTaskEvents(client, nil)
+	var rec eventRecorder
+	events.Subscribe(rec.handle)
+
+	require.NoError(t, events.Poll())
+	require.NoError(t, events.Poll())
+
+	mu.Lock()
+	status = TaskStatusCompleted
+	output = `[{"role":"user","content":"Go"},{"role":"assistant","content":"Done"}]`
+	mu.Unlock()
+	require.NoError(t, events.Poll())
+	require.NoError(t, events.Poll())
+
+	assert.Equal(t, []TaskEventType{
+		TaskEventCreated, TaskEventMessage, TaskEventProgressed,
+		TaskEventMessage, TaskEventStopped,
+	}, rec.types())
+
+	events.HandleWebhook(&WebhookPayload{EventType: WebhookEventTaskStopped, TaskDetail: map[string]interface{}{
+		"task_id": "task_1", "stop_reason": StopReasonFinish, "message": "Done",
+	}})
+	assert.Len(t, rec.types(), 5)
+}
+
+func TestTaskEventsIdenticalMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/tasks" {
+			w.Write([]byte(`{"data":[{"id":"task_1","status":"running"}]}`))
+			return
+		}
+		w.Write([]byte(`{"id":"task_1","status":"running","output":[
+			{"role":"assistant","content":"OK"},{"role":"user","content":"Next"},{"role":"assistant","content":"OK"}]}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	events := NewTaskEvents(client, nil)
+	var rec eventRecorder
+	events.Subscribe(rec.handle)
+
+	require.NoError(t, events.Poll())
+	require.NoError(t, events.Poll())
+
+	var contents []string
+	for _, e := range rec.events {
+		if e.Type == TaskEventMessage {
+			contents = append(contents, e.Message.Content)
+		}
+	}
+	assert.Equal(t, []string{"OK", "Next", "OK"}, contents)
+}
+
+func TestTaskEventsHandlerReentersAndStateIsDropped(t *testing.T) {
+	events := NewTaskEvents(nil, nil)
+	var rec eventRecorder
+	stopped := &WebhookPayload{EventType: WebhookEventTaskStopped, TaskDetail: map[string]interface{}{
+		"task_id": "task_1", "stop_reason": StopReasonFinish,
+	}}
+	events.Subscribe(func(e TaskEvent) {
+		rec.handle(e)
+		if e.Type == TaskEventCreated {
+			events.HandleWebhook(stopped)
+		}
+	})
+
+	events.HandleWebhook(&WebhookPayload{EventType: WebhookEventTaskCreated, TaskDetail: map[string]interface{}{"task_id": "task_1"}})
+	assert.Equal(t, []TaskEventType{TaskEventCreated, TaskEventStopped}, rec.types())
+
+	events.mu.Lock()
+	assert.Empty(t, events.tasks)
+	events.mu.Unlock()
+
+	events.HandleWebhook(&WebhookPayload{EventType: WebhookEventTaskCreated, TaskDetail: map[string]interface{}{"task_id": "task_1"}})
+	assert.Len(t, rec.types(), 2)
+}
+
+func TestTaskEventsWithoutClient(t *testing.T) {
+	events := NewTaskEvents(nil, nil)
+	assert.IsType(t, &ValidationError{}, events.Poll())
+	assert.IsType(t, &ValidationError{}, events.Run(context.Background()))
+}
+
+func TestTaskEventsRunCancelsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	events := NewTaskEvents(client, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, events.Run(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -21,18 +22,17 @@ func main() {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	fmt.Println("=== Creating Webhook ===")
-	webhook := &manusai.WebhookConfig{
-		URL:    "https://your-domain.com/webhook/manus-ai",
-		Events: []string{"task_created", "task_stopped"},
-	}
-
-	webhookResult, err := client.CreateWebhook(webhook)
+	fmt.Println("=== Registering Webhook ===")
+	webhookResult, err := client.EnsureWebhook(
+		context.Background(),
+		"https://your-domain.com/webhook/manus-ai",
+		[]string{"task_created", "task_stopped"},
+	)
 	if err != nil {
-		log.Fatalf("Failed to create webhook: %v", err)
+		log.Fatalf("Failed to register webhook: %v", err)
 	}
 
-	fmt.Printf("Webhook created successfully!\n")
+	fmt.Printf("Webhook registered successfully!\n")
 	fmt.Printf("Webhook ID: %s\n", webhookResult.WebhookID)
 
 	fmt.Println("\n=== Starting Webhook Server ===")
@@ -0,0 +1,142 @@
+/*
+Package export renders Manus AI tasks as human-readable transcripts and as a
+stable JSON archive format that can be read back with ReadArchive.
+*/
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	manusai "github.com/tigusigalpa/manus-ai-go"
+)
+
+type Format string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatHTML     Format = "html"
+	FormatJSON     Format = "json"
+)
+
+const ArchiveVersion = 1
+
+type Archive struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Metadata   map[string]string  `json:"metadata,omitempty"`
+	Task       manusai.TaskDetail `json:"task"`
+}
+
+type TaskSource interface {
+	GetTasks(filters *manusai.TaskFilters) (*manusai.TaskListResponse, error)
+	GetTask(taskID string) (*manusai.TaskDetail, error)
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q", s)
+	}
+}
+
+func NewArchive(detail *manusai.TaskDetail) *Archive {
+	return &Archive{
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Task:       *detail,
+	}
+}
+
+func Write(w io.Writer, archive *Archive, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return Markdown(w, archive)
+	case FormatHTML:
+		return HTML(w, archive)
+	case FormatJSON:
+		return JSON(w, archive)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+func JSON(w io.Writer, archive *Archive) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(archive)
+}
+
+func ReadArchive(r io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("invalid task archive: %w", err)
+	}
+	if archive.Version == 0 || archive.Version > ArchiveVersion {
+		return nil, fmt.Errorf("unsupported task archive version %d", archive.Version)
+	}
+	return &archive, nil
+}
+
+// Tasks exports every task matching filters into dir, one file per task,
+// following the After cursor until the listing is exhausted.
+func Tasks(source TaskSource, filters *manusai.TaskFilters, dir string, format Format) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var query manusai.TaskFilters
+	if filters != nil {
+		query = *filters
+	}
+
+	var paths []string
+	for {
+		list, err := source.GetTasks(&query)
+		if err != nil {
+			return paths, err
+		}
+
+		for _, summary := range list.Data {
+			detail, err := source.GetTask(summary.ID)
+			if err != nil {
+				return paths, fmt.Errorf("task %s: %w", summary.ID, err)
+			}
+
+			path := filepath.Join(dir, manusai.SanitizeFileName(summary.ID)+"."+string(format))
+			if err := writeFile(path, NewArchive(detail), format); err != nil {
+				return paths, fmt.Errorf("task %s: %w", summary.ID, err)
+			}
+			paths = append(paths, path)
+		}
+
+		if !list.HasMore || len(list.Data) == 0 {
+			return paths, nil
+		}
+		query.After = list.Data[len(list.Data)-1].ID
+	}
+}
+
+func writeFile(path string, archive *Archive, format Format) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := Write(file, archive, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	manusai "github.com/tigusigalpa/manus-ai-go"
+)
+
+func testDetail() *manusai.TaskDetail {
+	return &manusai.TaskDetail{
+		ID:          "task_123",
+		Title:       "Quarterly <report>",
+		Status:      "completed",
+		CreditUsage: 4.5,
+		Output: []manusai.TaskMessage{
+			{Role: "user", Content: "Summarize the data"},
+			{Role: "assistant", Content: "Here is the <b>summary</b>"},
+		},
+		Attachments: []manusai.OutputAttachment{
+			{FileName: "summary.pdf", URL: "https://files.example.com/summary.pdf", SizeBytes: 2048},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("markdown")
+	require.NoError(t, err)
+	assert.Equal(t, FormatMarkdown, format)
+
+	format, err = ParseFormat("HTML")
+	require.NoError(t, err)
+	assert.Equal(t, FormatHTML, format)
+
+	_, err = ParseFormat("pdf")
+	assert.Error(t, err)
+}
+
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Markdown(&buf, NewArchive(testDetail())))
+
+	out := buf.String()
+	assert.Contains(t, out, "# Quarterly <report>")
+	assert.Contains(t, out, "| Credits | 4.50 |")
+	assert.Contains(t, out, "### 2. Assistant")
+	assert.Contains(t, out, "- [summary.pdf](<https://files.example.com/summary.pdf>) (2048 bytes)")
+}
+
+func TestMarkdownEscapesLinksAndRoles(t *testing.T) {
+	detail := testDetail()
+	detail.Output = []manusai.TaskMessage{{Role: "élève", Content: "Bonjour"}}
+	detail.Attachments = []manusai.OutputAttachment{
+		{FileName: "chart (1).png", URL: "https://files.example.com/chart (1)<x>.png"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Markdown(&buf, NewArchive(detail)))
+
+	out := buf.String()
+	assert.Contains(t, out, "### 1. Élève")
+	assert.Contains(t, out, "(<https://files.example.com/chart (1)%3Cx%3E.png>)")
+}
+
+func TestRoleLabel(t *testing.T) {
+	assert.Equal(t, "Message", roleLabel(""))
+	assert.Equal(t, "User", roleLabel("user"))
+	assert.Equal(t, "Ölçü", roleLabel("ölçü"))
+}
+
+func TestHTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, HTML(&buf, NewArchive(testDetail())))
+
+	out := buf.String()
+	assert.Contains(t, out, "<title>Quarterly &lt;report&gt;</title>")
+	assert.Contains(t, out, "Here is the &lt;b&gt;summary&lt;/b&gt;")
+	assert.Contains(t, out, `<a href="https://files.example.com/summary.pdf">summary.pdf</a>`)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	archive := NewArchive(testDetail())
+	archive.Metadata = map[string]string{"customer": "42"}
+
+	var buf bytes.Buffer
+	require.NoError(t, JSON(&buf, archive))
+
+	restored, err := ReadArchive(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveVersion, restored.Version)
+	assert.Equal(t, archive.Task, restored.Task)
+	assert.Equal(t, "42", restored.Metadata["customer"])
+
+	_, err = ReadArchive(bytes.NewBufferString(`{"version":99}`))
+	assert.Error(t, err)
+}
+
+type fakeSource struct {
+	pages []manusai.TaskListResponse
+	calls []manusai.TaskFilters
+}
+
+func (f *fakeSource) GetTasks(filters *manusai.TaskFilters) (*manusai.TaskListResponse, error) {
+	f.calls = append(f.calls, *filters)
+	page := f.pages[len(f.calls)-1]
+	return &page, nil
+}
+
+func (f *fakeSource) GetTask(taskID string) (*manusai.TaskDetail, error) {
+	detail := testDetail()
+	detail.ID = taskID
+	return detail, nil
+}
+
+func TestTasks(t *testing.T) {
+	source := &fakeSource{pages: []manusai.TaskListResponse{
+		{Data: []manusai.TaskSummary{{ID: "task_1"}, {ID: "task_2"}}, HasMore: true},
+		{Data: []manusai.TaskSummary{{ID: "task_3"}}},
+	}}
+	dir := t.TempDir()
+
+	paths, err := Tasks(source, &manusai.TaskFilters{Status: []string{"completed"}}, dir, FormatJSON)
+	require.NoError(t, err)
+	assert.Len(t, paths, 3)
+	assert.Equal(t, "task_2", source.calls[1].After)
+	assert.Equal(t, []string{"completed"}, source.calls[1].Status)
+
+	file, err := os.Open(filepath.Join(dir, "task_3.json"))
+	require.NoError(t, err)
+	defer file.Close()
+	archive, err := ReadArchive(file)
+	require.NoError(t, err)
+	assert.Equal(t, "task_3", archive.Task.ID)
+}
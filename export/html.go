@@ -0,0 +1,65 @@
+package export
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"role": roleLabel,
+	"keys": sortedKeys,
+	"inc":  func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .Task.Title}}{{.Task.Title}}{{else}}{{.Task.ID}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 860px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+table { border-collapse: collapse; margin-bottom: 2rem; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; }
+th { background: #f6f8fa; }
+.message { border-left: 4px solid #d0d7de; padding: 0.5rem 1rem; margin: 1rem 0; }
+.message.assistant { border-color: #0969da; }
+.message.user { border-color: #1a7f37; }
+.role { font-weight: 600; margin-bottom: 0.5rem; }
+.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{if .Task.Title}}{{.Task.Title}}{{else}}{{.Task.ID}}{{end}}</h1>
+<table>
+<tr><th>Task ID</th><td><code>{{.Task.ID}}</code></td></tr>
+<tr><th>Status</th><td>{{.Task.Status}}</td></tr>
+<tr><th>Credits</th><td>{{printf "%.2f" .Task.CreditUsage}}</td></tr>
+<tr><th>Created</th><td>{{.Task.CreatedAt}}</td></tr>
+<tr><th>Updated</th><td>{{.Task.UpdatedAt}}</td></tr>
+{{- range $k := keys .Metadata}}
+<tr><th>{{$k}}</th><td>{{index $.Metadata $k}}</td></tr>
+{{- end}}
+<tr><th>Exported</th><td>{{.ExportedAt.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+</table>
+<h2>Transcript</h2>
+{{- range $i, $m := .Task.Output}}
+<div class="message {{$m.Role}}">
+<div class="role">{{inc $i}}. {{role $m.Role}}</div>
+<div class="content">{{$m.Content}}</div>
+</div>
+{{- else}}
+<p><em>No messages.</em></p>
+{{- end}}
+{{- if .Task.Attachments}}
+<h2>Attachments</h2>
+<ul>
+{{- range .Task.Attachments}}
+<li><a href="{{.URL}}">{{.FileName}}</a>{{if .SizeBytes}} ({{.SizeBytes}} bytes){{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+func HTML(w io.Writer, archive *Archive) error {
+	return htmlTemplate.Execute(w, archive)
+}
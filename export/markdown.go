@@ -0,0 +1,88 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func Markdown(w io.Writer, archive *Archive) error {
+	bw := bufio.NewWriter(w)
+	task := archive.Task
+
+	title := task.Title
+	if title == "" {
+		title = task.ID
+	}
+	fmt.Fprintf(bw, "# %s\n\n", escapeMarkdownLine(title))
+
+	fmt.Fprintf(bw, "| Field | Value |\n|---|---|\n")
+	fmt.Fprintf(bw, "| Task ID | `%s` |\n", task.ID)
+	fmt.Fprintf(bw, "| Status | %s |\n", escapeMarkdownCell(task.Status))
+	fmt.Fprintf(bw, "| Credits | %.2f |\n", task.CreditUsage)
+	fmt.Fprintf(bw, "| Created | %s |\n", escapeMarkdownCell(task.CreatedAt))
+	fmt.Fprintf(bw, "| Updated | %s |\n", escapeMarkdownCell(task.UpdatedAt))
+	for _, key := range sortedKeys(archive.Metadata) {
+		fmt.Fprintf(bw, "| %s | %s |\n", escapeMarkdownCell(key), escapeMarkdownCell(archive.Metadata[key]))
+	}
+	fmt.Fprintf(bw, "| Exported | %s |\n\n", archive.ExportedAt.Format("2006-01-02T15:04:05Z07:00"))
+
+	fmt.Fprintf(bw, "## Transcript\n\n")
+	if len(task.Output) == 0 {
+		fmt.Fprintf(bw, "_No messages._\n\n")
+	}
+	for i, msg := range task.Output {
+		fmt.Fprintf(bw, "### %d. %s\n\n", i+1, escapeMarkdownLine(roleLabel(msg.Role)))
+		fmt.Fprintf(bw, "%s\n\n", strings.TrimRight(msg.Content, "\n"))
+	}
+
+	if len(task.Attachments) > 0 {
+		fmt.Fprintf(bw, "## Attachments\n\n")
+		for _, att := range task.Attachments {
+			fmt.Fprintf(bw, "- [%s](%s)", escapeMarkdownLine(att.FileName), markdownLinkTarget(att.URL))
+			if att.SizeBytes > 0 {
+				fmt.Fprintf(bw, " (%d bytes)", att.SizeBytes)
+			}
+			fmt.Fprintf(bw, "\n")
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+
+	return bw.Flush()
+}
+
+func roleLabel(role string) string {
+	if role == "" {
+		return "Message"
+	}
+	r, size := utf8.DecodeRuneInString(role)
+	return string(unicode.ToUpper(r)) + role[size:]
+}
+
+// markdownLinkTarget wraps a URL in angle brackets so parentheses and spaces
+// do not end the link, percent-encoding what the brackets cannot hold.
+func markdownLinkTarget(url string) string {
+	return "<" + strings.NewReplacer("<", "%3C", ">", "%3E", "\n", "%0A", "\r", "%0D").Replace(url) + ">"
+}
+
+func escapeMarkdownLine(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.NewReplacer("[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_", "`", "\\`", "#", "\\#").Replace(s)
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(escapeMarkdownLine(s), "|", "\\|")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package manusai
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultFileWaitInterval    = time.Second
+	DefaultFileWaitMaxInterval = 10 * time.Second
+	DefaultFileWaitTimeout     = 5 * time.Minute
+)
+
+// FileStatus is the lifecycle state of an uploaded file. A file starts out
+// pending, becomes uploaded once its content has been PUT, may be processing
+// while the service indexes it, and ends up ready, failed or expired. Only
+// ready files can be attached to tasks.
+type FileStatus string
+
+const (
+	FileStatusPending    FileStatus = "pending"
+	FileStatusUploaded   FileStatus = "uploaded"
+	FileStatusProcessing FileStatus = "processing"
+	FileStatusReady      FileStatus = "ready"
+	FileStatusFailed     FileStatus = "failed"
+	FileStatusExpired    FileStatus = "expired"
+)
+
+// IsTerminal reports whether the status can no longer change.
+func (s FileStatus) IsTerminal() bool {
+	return s == FileStatusReady || s == FileStatusFailed || s == FileStatusExpired
+}
+
+// FileStatus returns Status as a FileStatus.
+func (f FileResponse) FileStatus() FileStatus {
+	return FileStatus(f.Status)
+}
+
+// FileStatus returns Status as a FileStatus.
+func (f FileDetail) FileStatus() FileStatus {
+	return FileStatus(f.Status)
+}
+
+// FileWaitConfig controls how WaitForFile polls GetFile. The interval
+// doubles after every poll up to MaxInterval.
+type FileWaitConfig struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Timeout bounds the whole wait; negative disables it.
+	Timeout time.Duration
+}
+
+func WithFileWaitConfig(config FileWaitConfig) ClientOption {
+	return func(c *Client) {
+		if config.Interval > 0 {
+			c.fileWait.Interval = config.Interval
+		}
+		if config.MaxInterval > 0 {
+			c.fileWait.MaxInterval = config.MaxInterval
+		}
+		if config.Timeout != 0 {
+			c.fileWait.Timeout = config.Timeout
+		}
+	}
+}
+
+// WaitForFile polls the file until it is ready. It returns a FileStatusError
+// if the file fails or expires, and the context error if ctx is done or the
+// configured timeout passes first. Unknown statuses are treated as not yet
+// ready.
+func (c *Client) WaitForFile(ctx context.Context, fileID string) (*FileDetail, error) {
+	if c.fileWait.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.fileWait.Timeout)
+		defer cancel()
+	}
+
+	interval := c.fileWait.Interval
+	var last *FileDetail
+	for {
+		file, err := c.getFile(ctx, fileID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			return nil, err
+		}
+		last = file
+
+		switch file.FileStatus() {
+		case FileStatusReady:
+			return file, nil
+		case FileStatusFailed, FileStatusExpired:
+			return file, &FileStatusError{FileID: fileID, Status: file.FileStatus()}
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return file, ctx.Err()
+		}
+		if interval *= 2; interval > c.fileWait.MaxInterval {
+			interval = c.fileWait.MaxInterval
+		}
+	}
+}
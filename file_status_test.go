@@ -0,0 +1,110 @@
+package manusai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFileStatusServer serves GET /v1/files/{id} walking through statuses in
+// order, then repeating the last one.
+func newFileStatusServer(t *testing.T, statuses map[string][]FileStatus) (*Client, *[]string) {
+	var mu sync.Mutex
+	polls := make(map[string]int)
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		if r.Method == "POST" && r.URL.Path == "/v1/tasks" {
+			w.Write([]byte(`{"task_id":"task_1"}`))
+			return
+		}
+		id := r.URL.Path[len("/v1/files/"):]
+		seq, ok := statuses[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		n := polls[id]
+		if n >= len(seq) {
+			n = len(seq) - 1
+		}
+		polls[id]++
+		json.NewEncoder(w).Encode(FileDetail{ID: id, Status: string(seq[n])})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL),
+		WithFileWaitConfig(FileWaitConfig{Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond}))
+	require.NoError(t, err)
+	return client, &requests
+}
+
+func TestFileStatusIsTerminal(t *testing.T) {
+	assert.True(t, FileStatusReady.IsTerminal())
+	assert.True(t, FileStatusFailed.IsTerminal())
+	assert.True(t, FileStatusExpired.IsTerminal())
+	assert.False(t, FileStatusPending.IsTerminal())
+	assert.False(t, FileStatusProcessing.IsTerminal())
+	assert.False(t, FileStatus("indexing").IsTerminal())
+}
+
+func TestWaitForFile(t *testing.T) {
+	client, requests := newFileStatusServer(t, map[string][]FileStatus{
+		"file_1": {FileStatusPending, FileStatusUploaded, FileStatusProcessing, FileStatusReady},
+		"file_2": {FileStatusProcessing, FileStatusFailed},
+	})
+
+	file, err := client.WaitForFile(context.Background(), "file_1")
+	require.NoError(t, err)
+	assert.Equal(t, FileStatusReady, file.FileStatus())
+	assert.Len(t, *requests, 4)
+
+	_, err = client.WaitForFile(context.Background(), "file_2")
+	var statusErr *FileStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, FileStatusFailed, statusErr.Status)
+}
+
+func TestWaitForFileTimeout(t *testing.T) {
+	client, _ := newFileStatusServer(t, map[string][]FileStatus{"file_1": {FileStatusProcessing}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	file, err := client.WaitForFile(ctx, "file_1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotNil(t, file)
+	assert.Equal(t, FileStatusProcessing, file.FileStatus())
+}
+
+func TestCreateTaskWaitForFiles(t *testing.T) {
+	client, requests := newFileStatusServer(t, map[string][]FileStatus{
+		"file_1": {FileStatusUploaded, FileStatusReady},
+		"file_2": {FileStatusExpired},
+	})
+
+	_, err := client.CreateTask("Summarize", &TaskOptions{
+		Attachments:  []interface{}{NewAttachmentFromFileID("file_1"), NewAttachmentFromURL("https://example.com/doc.pdf")},
+		WaitForFiles: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET /v1/files/file_1", "GET /v1/files/file_1", "POST /v1/tasks"}, *requests)
+
+	_, err = client.CreateTask("Summarize", &TaskOptions{
+		Attachments:  []interface{}{NewAttachmentFromFileID("file_2")},
+		WaitForFiles: true,
+	})
+	assert.IsType(t, &FileStatusError{}, err)
+	assert.Equal(t, "POST /v1/tasks", (*requests)[2])
+	assert.Len(t, *requests, 4)
+}
@@ -0,0 +1,357 @@
+package manusai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAwaitWebhookTimeout = 2 * time.Minute
+	DefaultAwaitPollInterval   = 10 * time.Second
+
+	maxEarlyStops = 1024
+	// maxPendingHandles bounds the handles waiting for a webhook. The
+	// oldest beyond it stop waiting and resolve by polling when awaited.
+	maxPendingHandles = 4096
+)
+
+type AwaitConfig struct {
+	WebhookTimeout time.Duration
+	PollInterval   time.Duration
+}
+
+func WithAwaitConfig(config AwaitConfig) ClientOption {
+	return func(c *Client) {
+		if config.WebhookTimeout > 0 {
+			c.await.WebhookTimeout = config.WebhookTimeout
+		}
+		if config.PollInterval > 0 {
+			c.await.PollInterval = config.PollInterval
+		}
+	}
+}
+
+// TaskHandle is a future for a submitted task. It is resolved by the
+// task_stopped webhook passed to Client.ObserveWebhook, or by polling GetTask
+// once AwaitConfig.WebhookTimeout passes without a webhook. A client tracks
+// at most 4096 unresolved handles for webhooks; older ones are left to
+// polling.
+//
+// If the task fails and TaskOptions.ProfileSelector can escalate, Await
+// submits it again with a stronger profile and waits for that attempt; see
+// Attempts.
+type TaskHandle struct {
+	TaskID   string
+	Response *TaskResponse
+
+	client  *Client
+	stopped chan struct{}
+	payload *WebhookPayload
+	prompt  string
+	options *TaskOptions
+
+	mu     sync.Mutex
+	detail *TaskDetail
+	retry  *TaskHandle
+}
+
+func (c *Client) Submit(ctx context.Context, prompt string, options *TaskOptions) (*TaskHandle, error) {
+	resp, err := c.createTask(ctx, prompt, options)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &TaskHandle{
+		TaskID:   resp.TaskID,
+		Response: resp,
+		client:   c,
+		stopped:  make(chan struct{}),
+		prompt:   prompt,
+		options:  options,
+	}
+	c.waiters.register(h)
+
+	return h, nil
+}
+
+// Await blocks until the task stops and returns its latest detail. A stop
+// with reason "ask" also resolves the handle; check the returned status.
+func (h *TaskHandle) Await(ctx context.Context) (*TaskDetail, error) {
+	current := h
+	for {
+		detail, err := current.wait(ctx)
+		if err != nil || detail.Status != TaskStatusFailed {
+			return detail, err
+		}
+
+		next, err := current.escalate(ctx)
+		if next == nil || err != nil {
+			return detail, err
+		}
+		current = next
+	}
+}
+
+// Attempts returns the response for this task followed by those of any
+// escalated retries, in order.
+func (h *TaskHandle) Attempts() []*TaskResponse {
+	var attempts []*TaskResponse
+	for current := h; current != nil; {
+		attempts = append(attempts, current.Response)
+		current.mu.Lock()
+		next := current.retry
+		current.mu.Unlock()
+		current = next
+	}
+	return attempts
+}
+
+// escalate returns the retry of a failed task, submitting it with a
+// stronger profile on first use. It returns nil if the selector has none.
+func (h *TaskHandle) escalate(ctx context.Context) (*TaskHandle, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.retry != nil {
+		return h.retry, nil
+	}
+	if h.options == nil || h.options.ProfileSelector == nil {
+		return nil, nil
+	}
+
+	// Escalate from the profile the selector chose or was given, not the
+	// one DeprecationPolicy may have migrated it to.
+	current := h.Response.requestedProfile
+	if current == "" {
+		current = h.Response.AgentProfile
+	}
+	profile, ok := h.options.ProfileSelector.Escalate(newProfileRequest(h.prompt, h.options), current)
+	if !ok {
+		return nil, nil
+	}
+
+	options := *h.options
+	options.AgentProfile = profile
+	options.IdempotencyKey = ""
+	retry, err := h.client.Submit(ctx, h.prompt, &options)
+	if err != nil {
+		return nil, err
+	}
+	h.retry = retry
+	return retry, nil
+}
+
+func (h *TaskHandle) wait(ctx context.Context) (*TaskDetail, error) {
+	h.mu.Lock()
+	if h.detail != nil {
+		defer h.mu.Unlock()
+		return h.detail, nil
+	}
+	h.mu.Unlock()
+
+	// The handle stays registered until it resolves, so a webhook arriving
+	// after a cancelled Await still reaches the next one.
+	timer := time.NewTimer(h.client.await.WebhookTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-h.stopped:
+		return h.resolve(ctx)
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	ticker := time.NewTicker(h.client.await.PollInterval)
+	defer ticker.Stop()
+
+	sawRunning := false
+	for {
+		detail, err := h.client.getTask(ctx, h.TaskID)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil && taskStoppedPolled(detail, sawRunning) {
+			return h.finish(detail), nil
+		}
+		if err == nil && detail.Status == TaskStatusRunning {
+			sawRunning = true
+		}
+
+		select {
+		case <-h.stopped:
+			return h.resolve(ctx)
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Done is closed when the task_stopped webhook for this task is observed.
+func (h *TaskHandle) Done() <-chan struct{} {
+	return h.stopped
+}
+
+// Webhook returns the task_stopped payload that resolved the handle, or nil
+// if it has not been received.
+func (h *TaskHandle) Webhook() *WebhookPayload {
+	select {
+	case <-h.stopped:
+		return h.payload
+	default:
+		return nil
+	}
+}
+
+func (h *TaskHandle) resolve(ctx context.Context) (*TaskDetail, error) {
+	detail, err := h.client.getTask(ctx, h.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	return h.finish(detail), nil
+}
+
+func (h *TaskHandle) finish(detail *TaskDetail) *TaskDetail {
+	h.client.waiters.unregister(h)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.detail = detail
+	return detail
+}
+
+// taskStoppedPolled reports whether a polled task has stopped the way a
+// task_stopped webhook would report it: finished, failed, or back to pending
+// to ask for input after running or producing output.
+func taskStoppedPolled(detail *TaskDetail, sawRunning bool) bool {
+	switch detail.Status {
+	case TaskStatusCompleted, TaskStatusFailed:
+		return true
+	case TaskStatusPending:
+		if sawRunning {
+			return true
+		}
+		for _, msg := range detail.Output {
+			if msg.Role == "assistant" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// taskWaiters correlates task_stopped webhooks with pending handles. Stops
+// that arrive before Submit has registered its handle are kept briefly so
+// the race between CreateTask returning and the webhook is harmless.
+type taskWaiters struct {
+	mu      sync.Mutex
+	pending map[string][]*TaskHandle
+	// pendingOrder lists registered handles oldest first. Handles that have
+	// since resolved are dropped from it lazily; pendingCount counts the
+	// ones still in pending.
+	pendingOrder []*TaskHandle
+	pendingCount int
+	early        map[string]*WebhookPayload
+	earlyOrder   []string
+}
+
+func (w *taskWaiters) register(h *TaskHandle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if payload, ok := w.early[h.TaskID]; ok {
+		delete(w.early, h.TaskID)
+		for i, id := range w.earlyOrder {
+			if id == h.TaskID {
+				w.earlyOrder = append(w.earlyOrder[:i:i], w.earlyOrder[i+1:]...)
+				break
+			}
+		}
+		h.payload = payload
+		close(h.stopped)
+		return
+	}
+
+	if w.pending == nil {
+		w.pending = make(map[string][]*TaskHandle)
+	}
+	w.pending[h.TaskID] = append(w.pending[h.TaskID], h)
+	w.pendingOrder = append(w.pendingOrder, h)
+	w.pendingCount++
+
+	for w.pendingCount > maxPendingHandles {
+		oldest := w.pendingOrder[0]
+		w.pendingOrder = w.pendingOrder[1:]
+		w.removeLocked(oldest)
+	}
+	if len(w.pendingOrder) > 2*maxPendingHandles {
+		live := make([]*TaskHandle, 0, w.pendingCount)
+		for _, other := range w.pendingOrder {
+			if w.isPendingLocked(other) {
+				live = append(live, other)
+			}
+		}
+		w.pendingOrder = live
+	}
+}
+
+func (w *taskWaiters) unregister(h *TaskHandle) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.removeLocked(h)
+}
+
+func (w *taskWaiters) isPendingLocked(h *TaskHandle) bool {
+	for _, other := range w.pending[h.TaskID] {
+		if other == h {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *taskWaiters) removeLocked(h *TaskHandle) {
+	handles := w.pending[h.TaskID]
+	for i, other := range handles {
+		if other == h {
+			handles = append(handles[:i:i], handles[i+1:]...)
+			w.pendingCount--
+			break
+		}
+	}
+	if len(handles) == 0 {
+		delete(w.pending, h.TaskID)
+	} else {
+		w.pending[h.TaskID] = handles
+	}
+}
+
+func (w *taskWaiters) resolve(taskID string, payload *WebhookPayload) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	handles, ok := w.pending[taskID]
+	if !ok {
+		if w.early == nil {
+			w.early = make(map[string]*WebhookPayload)
+		}
+		if _, seen := w.early[taskID]; !seen {
+			w.earlyOrder = append(w.earlyOrder, taskID)
+		}
+		w.early[taskID] = payload
+		for len(w.earlyOrder) > maxEarlyStops {
+			delete(w.early, w.earlyOrder[0])
+			w.earlyOrder = w.earlyOrder[1:]
+		}
+		return
+	}
+
+	delete(w.pending, taskID)
+	w.pendingCount -= len(handles)
+	for _, h := range handles {
+		h.payload = payload
+		close(h.stopped)
+	}
+}
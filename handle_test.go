@@ -0,0 +1,218 @@
+package manusai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHandleTestServer(t *testing.T, status *atomic.Value, gets *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(`{"task_id":"task_123"}`))
+			return
+		}
+		atomic.AddInt32(gets, 1)
+		w.Write([]byte(`{"id":"task_123","status":"` + status.Load().(string) + `"}`))
+	}))
+}
+
+func TestSubmitAwaitWebhook(t *testing.T) {
+	var status atomic.Value
+	status.Store(TaskStatusCompleted)
+	var gets int32
+	server := newHandleTestServer(t, &status, &gets)
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+
+	handle, err := client.Submit(context.Background(), "Test prompt", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "task_123", handle.TaskID)
+
+	go client.ObserveWebhook(&WebhookPayload{
+		EventType:  WebhookEventTaskStopped,
+		TaskDetail: map[string]interface{}{"task_id": "task_123", "stop_reason": StopReasonFinish},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	detail, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, detail.Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&gets))
+	assert.NotNil(t, handle.Webhook())
+
+	again, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Same(t, detail, again)
+}
+
+func TestSubmitAwaitEarlyWebhook(t *testing.T) {
+	var status atomic.Value
+	status.Store(TaskStatusCompleted)
+	var gets int32
+	server := newHandleTestServer(t, &status, &gets)
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	client.ObserveWebhook(&WebhookPayload{
+		EventType:  WebhookEventTaskStopped,
+		TaskDetail: map[string]interface{}{"task_id": "task_123", "stop_reason": StopReasonFinish},
+	})
+
+	handle, err := client.Submit(context.Background(), "Test prompt", nil)
+	require.NoError(t, err)
+
+	select {
+	case <-handle.Done():
+	default:
+		t.Fatal("handle should already be resolved")
+	}
+}
+
+func TestSubmitAwaitPollingFallback(t *testing.T) {
+	var status atomic.Value
+	status.Store(TaskStatusRunning)
+	var gets int32
+	server := newHandleTestServer(t, &status, &gets)
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL), WithAwaitConfig(AwaitConfig{
+		WebhookTimeout: 10 * time.Millisecond,
+		PollInterval:   10 * time.Millisecond,
+	}))
+
+	handle, err := client.Submit(context.Background(), "Test prompt", nil)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		status.Store(TaskStatusCompleted)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	detail, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, detail.Status)
+	assert.Greater(t, atomic.LoadInt32(&gets), int32(1))
+}
+
+func TestAwaitContextCancelled(t *testing.T) {
+	var status atomic.Value
+	status.Store(TaskStatusRunning)
+	var gets int32
+	server := newHandleTestServer(t, &status, &gets)
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	handle, err := client.Submit(context.Background(), "Test prompt", nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = handle.Await(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAwaitAfterCancelledAwaitStillGetsWebhook(t *testing.T) {
+	var status atomic.Value
+	status.Store(TaskStatusCompleted)
+	var gets int32
+	server := newHandleTestServer(t, &status, &gets)
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	handle, err := client.Submit(context.Background(), "Test prompt", nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err = handle.Await(ctx)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	client.ObserveWebhook(&WebhookPayload{
+		EventType:  WebhookEventTaskStopped,
+		TaskDetail: map[string]interface{}{"task_id": "task_123", "stop_reason": StopReasonFinish},
+	})
+	assert.Empty(t, client.waiters.early)
+	assert.Empty(t, client.waiters.earlyOrder)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	detail, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, detail.Status)
+	assert.NotNil(t, handle.Webhook())
+	assert.Empty(t, client.waiters.pending)
+}
+
+func TestSubmitAwaitPollingResolvesOnAsk(t *testing.T) {
+	var status atomic.Value
+	status.Store(TaskStatusRunning)
+	var gets int32
+	server := newHandleTestServer(t, &status, &gets)
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL), WithAwaitConfig(AwaitConfig{
+		WebhookTimeout: 10 * time.Millisecond,
+		PollInterval:   10 * time.Millisecond,
+	}))
+	handle, err := client.Submit(context.Background(), "Test prompt", nil)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		status.Store(TaskStatusPending)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	detail, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPending, detail.Status)
+	assert.Empty(t, client.waiters.pending)
+}
+
+func TestTaskWaitersEarlyStopCleanup(t *testing.T) {
+	var w taskWaiters
+	w.resolve("task_1", &WebhookPayload{})
+	w.resolve("task_2", &WebhookPayload{})
+
+	w.register(&TaskHandle{TaskID: "task_1", stopped: make(chan struct{})})
+	assert.Equal(t, []string{"task_2"}, w.earlyOrder)
+	assert.Len(t, w.early, 1)
+}
+
+func TestTaskWaitersPendingLimit(t *testing.T) {
+	var w taskWaiters
+	handles := make([]*TaskHandle, maxPendingHandles+1)
+	for i := range handles {
+		handles[i] = &TaskHandle{TaskID: fmt.Sprintf("task_%d", i), stopped: make(chan struct{})}
+		w.register(handles[i])
+		if i%2 == 1 && i < maxPendingHandles {
+			w.resolve(handles[i].TaskID, &WebhookPayload{})
+		}
+	}
+	assert.Len(t, w.pending, maxPendingHandles/2+1)
+
+	// Handles that are never awaited are evicted oldest first.
+	var newest *TaskHandle
+	for i := maxPendingHandles + 1; i < 3*maxPendingHandles; i++ {
+		newest = &TaskHandle{TaskID: fmt.Sprintf("task_%d", i), stopped: make(chan struct{})}
+		w.register(newest)
+	}
+	assert.Len(t, w.pending, maxPendingHandles)
+	assert.Equal(t, maxPendingHandles, w.pendingCount)
+	assert.LessOrEqual(t, len(w.pendingOrder), 2*maxPendingHandles)
+	assert.False(t, w.isPendingLocked(handles[0]))
+	assert.True(t, w.isPendingLocked(newest))
+}
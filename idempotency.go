@@ -0,0 +1,303 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// recoveryQueryLength bounds the prompt prefix used to search for a task
+	// that may have been created by a request whose response was lost.
+	recoveryQueryLength = 200
+	recoverySkew        = time.Minute
+
+	// idempotencySweepInterval limits how often put scans the cache for
+	// expired keys.
+	idempotencySweepInterval = time.Minute
+)
+
+type IdempotencyConfig struct {
+	// AutoKey generates a key for every CreateTask and CreateFile call that
+	// does not set one, so a lost response is recovered instead of creating
+	// a second task.
+	AutoKey bool
+	// TTL is how long a key is remembered in the client-side cache.
+	TTL time.Duration
+}
+
+type FileOptions struct {
+	IdempotencyKey string
+}
+
+func WithIdempotency(config IdempotencyConfig) ClientOption {
+	return func(c *Client) {
+		c.idempotency.autoKey = config.AutoKey
+		if config.TTL > 0 {
+			c.idempotency.ttl = config.TTL
+		}
+	}
+}
+
+// idempotencyCache maps idempotency keys to the resources they created, for
+// servers that do not honour the Idempotency-Key header themselves.
+type idempotencyCache struct {
+	autoKey bool
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]idempotencyEntry
+	claimed  map[string]bool
+	inflight map[string]chan struct{}
+	sweepAt  time.Time
+}
+
+type idempotencyEntry struct {
+	resource interface{}
+	id       string
+	expires  time.Time
+}
+
+func (c *idempotencyCache) key(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if c.autoKey {
+		return "idem_" + randomID()
+	}
+	return ""
+}
+
+func (c *idempotencyCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		delete(c.claimed, entry.id)
+		return nil, false
+	}
+	return entry.resource, true
+}
+
+func (c *idempotencyCache) put(key, id string, resource interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]idempotencyEntry)
+		c.claimed = make(map[string]bool)
+	}
+	ttl := c.ttl
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	now := time.Now()
+	if !now.Before(c.sweepAt) {
+		c.sweepLocked(now)
+		c.sweepAt = now.Add(idempotencySweepInterval)
+	}
+	c.entries[key] = idempotencyEntry{resource: resource, id: id, expires: now.Add(ttl)}
+	c.claimed[id] = true
+}
+
+// sweepLocked drops expired keys, which would otherwise stay until looked up
+// again; auto keys never are.
+func (c *idempotencyCache) sweepLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+			delete(c.claimed, entry.id)
+		}
+	}
+}
+
+// do returns the cached resource for key, or runs create and caches its
+// result. Concurrent calls with the same key wait for the first one instead
+// of creating a second resource; if it fails, the next waiter tries itself.
+func (c *idempotencyCache) do(ctx context.Context, key string, create func() (string, interface{}, error)) (interface{}, error) {
+	for {
+		if resource, ok := c.get(key); ok {
+			return resource, nil
+		}
+
+		c.mu.Lock()
+		done, busy := c.inflight[key]
+		if !busy {
+			if c.inflight == nil {
+				c.inflight = make(map[string]chan struct{})
+			}
+			done = make(chan struct{})
+			c.inflight[key] = done
+		}
+		c.mu.Unlock()
+
+		if busy {
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		id, resource, err := create()
+		if err == nil {
+			c.put(key, id, resource)
+		}
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(done)
+		return resource, err
+	}
+}
+
+// isClaimed reports whether a resource already belongs to another key, so
+// recovery does not hand the same task to two callers.
+func (c *idempotencyCache) isClaimed(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.claimed[id]
+}
+
+type idempotencyContextKey struct{}
+
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyContextKey{}, key)
+}
+
+func idempotencyKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyContextKey{}).(string)
+	return key
+}
+
+// submitIdempotent runs create and, when the outcome is unknown, looks for
+// the resource with find (if given) before running create once more with the
+// same key.
+func (c *Client) submitIdempotent(ctx context.Context, key string, create func(ctx context.Context) error, find func(ctx context.Context, since time.Time) (bool, error)) error {
+	ctx = withIdempotencyKey(ctx, key)
+	started := time.Now()
+
+	err := create(ctx)
+	if err == nil || key == "" || !isAmbiguousFailure(err) || ctx.Err() != nil {
+		return err
+	}
+
+	if find != nil {
+		if found, findErr := find(ctx, started.Add(-recoverySkew)); findErr == nil && found {
+			return nil
+		}
+	}
+
+	return create(ctx)
+}
+
+// recoverTask looks for the task created by a request whose response was
+// lost. It only adopts a task when exactly one recent, unclaimed task matches
+// the prompt prefix, or exactly one of several matches the whole prompt. The
+// task list does not carry task URLs, so TaskURL is left empty.
+func (c *Client) recoverTask(ctx context.Context, prompt string, since time.Time) (*TaskResponse, error) {
+	list, err := c.getTasks(ctx, &TaskFilters{
+		Query:        truncateRunes(prompt, recoveryQueryLength),
+		CreatedAfter: since.UTC().Format(time.RFC3339),
+		Order:        "asc",
+		OrderBy:      "created_at",
+		Limit:        10,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []TaskSummary
+	for _, summary := range list.Data {
+		if c.idempotency.isClaimed(summary.ID) {
+			continue
+		}
+		if created, err := time.Parse(time.RFC3339, summary.CreatedAt); err == nil && created.Before(since) {
+			continue
+		}
+		candidates = append(candidates, summary)
+	}
+
+	if len(candidates) > 1 {
+		var exact []TaskSummary
+		for _, summary := range candidates {
+			detail, err := c.getTask(ctx, summary.ID)
+			if err != nil {
+				return nil, err
+			}
+			if taskPrompt(detail) == prompt {
+				exact = append(exact, summary)
+			}
+		}
+		candidates = exact
+	}
+	if len(candidates) != 1 {
+		return nil, nil
+	}
+
+	found := candidates[0]
+	return &TaskResponse{TaskID: found.ID, TaskTitle: found.Title}, nil
+}
+
+// taskPrompt returns the first user message of a task.
+func taskPrompt(detail *TaskDetail) string {
+	for _, msg := range detail.Output {
+		if msg.Role == "user" {
+			return msg.Content
+		}
+	}
+	return ""
+}
+
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// isAmbiguousFailure reports whether the request may have been applied even
+// though it failed: the connection broke, the response was lost or the
+// server failed after accepting it.
+func isAmbiguousFailure(err error) bool {
+	var encodeErr *requestEncodingError
+	if errors.As(err, &encodeErr) {
+		return false
+	}
+	var apiErr *ManusAIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == 0 {
+		return true
+	}
+	return apiErr.StatusCode >= 500 && apiErr.StatusCode != http.StatusNotImplemented
+}
+
+// requestEncodingError marks a request that failed before it was sent, so
+// there is nothing to recover.
+type requestEncodingError struct {
+	err error
+}
+
+func (e *requestEncodingError) Error() string { return e.err.Error() }
+
+func (e *requestEncodingError) Unwrap() error { return e.err }
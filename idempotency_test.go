@@ -0,0 +1,218 @@
+package manusai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyTaskServer creates tasks but drops the connection before answering
+// the first dropResponses POSTs, as a timed-out request would.
+type flakyTaskServer struct {
+	mu            sync.Mutex
+	dropResponses int
+	posts         []string
+	created       []TaskSummary
+	searches      []string
+}
+
+func (s *flakyTaskServer) handler(t *testing.T, recordTasks bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/tasks":
+			s.posts = append(s.posts, r.Header.Get(IdempotencyKeyHeader))
+			id := fmt.Sprintf("task_%d", len(s.posts))
+			if recordTasks {
+				s.created = append(s.created, TaskSummary{ID: id, Title: "Report"})
+			}
+			if s.dropResponses > 0 {
+				s.dropResponses--
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				conn.Close()
+				return
+			}
+			json.NewEncoder(w).Encode(TaskResponse{TaskID: id})
+		case r.Method == "GET" && r.URL.Path == "/v1/tasks":
+			s.searches = append(s.searches, r.URL.Query().Get("query"))
+			assert.NotEmpty(t, r.URL.Query().Get("createdAfter"))
+			json.NewEncoder(w).Encode(TaskListResponse{Data: s.created})
+		case r.Method == "POST" && r.URL.Path == "/v1/files":
+			s.posts = append(s.posts, r.Header.Get(IdempotencyKeyHeader))
+			json.NewEncoder(w).Encode(FileResponse{ID: fmt.Sprintf("file_%d", len(s.posts)), Filename: "a.txt"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestCreateTaskIdempotencyKeyCache(t *testing.T) {
+	fake := &flakyTaskServer{}
+	server := httptest.NewServer(fake.handler(t, true))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	first, err := client.CreateTask("Build the report", &TaskOptions{IdempotencyKey: "order-42"})
+	require.NoError(t, err)
+	second, err := client.CreateTask("Build the report", &TaskOptions{IdempotencyKey: "order-42"})
+	require.NoError(t, err)
+
+	assert.Equal(t, first.TaskID, second.TaskID)
+	assert.Equal(t, []string{"order-42"}, fake.posts)
+
+	_, err = client.CreateTask("Build the report", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order-42", ""}, fake.posts)
+}
+
+func TestCreateTaskRecoversLostResponse(t *testing.T) {
+	fake := &flakyTaskServer{dropResponses: 1}
+	server := httptest.NewServer(fake.handler(t, true))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithIdempotency(IdempotencyConfig{AutoKey: true}))
+	require.NoError(t, err)
+
+	resp, err := client.CreateTask("Build the report", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "task_1", resp.TaskID)
+	assert.Len(t, fake.posts, 1)
+	assert.NotEmpty(t, fake.posts[0])
+	assert.Equal(t, []string{"Build the report"}, fake.searches)
+}
+
+func TestCreateTaskResubmitsWithSameKey(t *testing.T) {
+	fake := &flakyTaskServer{dropResponses: 1}
+	server := httptest.NewServer(fake.handler(t, false))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	resp, err := client.CreateTask("Build the report", &TaskOptions{IdempotencyKey: "order-7"})
+	require.NoError(t, err)
+	assert.Equal(t, "task_2", resp.TaskID)
+	assert.Equal(t, []string{"order-7", "order-7"}, fake.posts)
+}
+
+func TestCreateFileWithOptionsIdempotency(t *testing.T) {
+	fake := &flakyTaskServer{}
+	server := httptest.NewServer(fake.handler(t, false))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	first, err := client.CreateFileWithOptions("a.txt", &FileOptions{IdempotencyKey: "upload-1"})
+	require.NoError(t, err)
+	second, err := client.CreateFileWithOptions("a.txt", &FileOptions{IdempotencyKey: "upload-1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, []string{"upload-1"}, fake.posts)
+}
+
+func TestCreateTaskConcurrentSameKey(t *testing.T) {
+	var mu sync.Mutex
+	var posts int
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		posts++
+		n := posts
+		mu.Unlock()
+		<-release
+		json.NewEncoder(w).Encode(TaskResponse{TaskID: fmt.Sprintf("task_%d", n)})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	ids := make([]string, 5)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.CreateTask("Build the report", &TaskOptions{IdempotencyKey: "order-42"})
+			require.NoError(t, err)
+			ids[i] = resp.TaskID
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, posts)
+	for _, id := range ids {
+		assert.Equal(t, "task_1", id)
+	}
+}
+
+func TestRecoverTaskRequiresUnambiguousMatch(t *testing.T) {
+	prompt := strings.Repeat("é", recoveryQueryLength+10)
+	var query string
+	tasks := map[string]string{"task_a": prompt, "task_b": prompt + " and more"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/tasks" {
+			query = r.URL.Query().Get("query")
+			json.NewEncoder(w).Encode(TaskListResponse{Data: []TaskSummary{{ID: "task_a"}, {ID: "task_b"}}})
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/v1/tasks/")
+		json.NewEncoder(w).Encode(TaskDetail{ID: id, Output: []TaskMessage{{Role: "user", Content: tasks[id]}}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	found, err := client.recoverTask(context.Background(), prompt, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "task_a", found.TaskID)
+	assert.Empty(t, found.TaskURL, "the API did not return one")
+	assert.True(t, utf8.ValidString(query))
+	assert.Equal(t, recoveryQueryLength, utf8.RuneCountInString(query))
+
+	tasks["task_b"] = prompt
+	found, err = client.recoverTask(context.Background(), prompt, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestIdempotencyCacheSweepsExpiredKeys(t *testing.T) {
+	cache := &idempotencyCache{ttl: time.Millisecond}
+	for i := 0; i < 3; i++ {
+		cache.put(randomID(), fmt.Sprintf("task_%d", i), nil)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	cache.sweepAt = time.Time{}
+	cache.put("idem_new", "task_new", nil)
+	assert.Len(t, cache.entries, 1)
+	assert.Equal(t, map[string]bool{"task_new": true}, cache.claimed)
+}
+
+func TestIsAmbiguousFailure(t *testing.T) {
+	assert.True(t, isAmbiguousFailure(&ManusAIError{Message: "Request failed: EOF"}))
+	assert.True(t, isAmbiguousFailure(&ManusAIError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, isAmbiguousFailure(&ManusAIError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, isAmbiguousFailure(&ManusAIError{Message: "bad body", Err: &requestEncodingError{err: fmt.Errorf("bad body")}}))
+}
@@ -0,0 +1,666 @@
+package manusai
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInboxMaxAttempts = 5
+	DefaultInboxBackoff     = time.Second
+	DefaultInboxMaxBackoff  = time.Minute
+	DefaultInboxDedupeSize  = 10000
+)
+
+type InboxEntry struct {
+	ID         string          `json:"id"`
+	Key        string          `json:"key"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts,omitempty"`
+	LastError  string          `json:"last_error,omitempty"`
+}
+
+// WebhookJournal durably records inbox entries. Append must not return until
+// the entry is persisted. Pending returns entries that were appended but
+// neither marked done nor dead, in arrival order. Done returns the
+// de-duplication keys of entries already completed or dead-lettered.
+type WebhookJournal interface {
+	Append(entry *InboxEntry) error
+	MarkDone(entry *InboxEntry) error
+	MarkDead(entry *InboxEntry) error
+	Pending() ([]*InboxEntry, error)
+	Done() ([]string, error)
+}
+
+type WebhookInboxConfig struct {
+	Journal     WebhookJournal
+	Verifier    WebhookVerifier
+	Handler     func(ctx context.Context, payload *WebhookPayload) error
+	Workers     int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	DedupeSize  int
+	DedupeKey   func(payload *WebhookPayload) string
+	OnError     func(entry *InboxEntry, err error)
+}
+
+// WebhookInbox is an http.Handler that journals every verified delivery
+// before acknowledging it, then processes entries asynchronously with
+// retries. Entries left pending by a crash are replayed by Start.
+type WebhookInbox struct {
+	config WebhookInboxConfig
+	queue  chan *InboxEntry
+
+	// startMu keeps Start from reading the journal while ServeHTTP is
+	// appending an entry it decided not to queue.
+	startMu sync.RWMutex
+
+	mu       sync.Mutex
+	running  bool
+	inFlight map[string]bool
+	// queued holds the IDs of entries sent to the queue or being processed,
+	// and reserved counts the queue slots taken or promised to a sender.
+	queued   map[string]bool
+	reserved int
+	done     map[string]bool
+	order    []string
+
+	wg sync.WaitGroup
+}
+
+func NewWebhookInbox(config WebhookInboxConfig) (*WebhookInbox, error) {
+	if config.Journal == nil {
+		return nil, &ValidationError{Message: "Webhook inbox journal is required"}
+	}
+	if config.Handler == nil {
+		return nil, &ValidationError{Message: "Webhook inbox handler is required"}
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultInboxMaxAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultInboxBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultInboxMaxBackoff
+	}
+	if config.DedupeSize <= 0 {
+		config.DedupeSize = DefaultInboxDedupeSize
+	}
+	if config.DedupeKey == nil {
+		config.DedupeKey = WebhookDedupeKey
+	}
+
+	inbox := &WebhookInbox{
+		config:   config,
+		queue:    make(chan *InboxEntry, 1024),
+		inFlight: make(map[string]bool),
+		queued:   make(map[string]bool),
+		done:     make(map[string]bool),
+	}
+
+	keys, err := config.Journal.Done()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		inbox.rememberDoneLocked(key)
+	}
+
+	return inbox, nil
+}
+
+// Start replays pending journal entries and runs the workers until ctx is
+// cancelled. It returns once all workers have stopped; entries not yet
+// processed stay pending in the journal for the next Start.
+func (in *WebhookInbox) Start(ctx context.Context) error {
+	in.startMu.Lock()
+	pending, err := in.config.Journal.Pending()
+	if err == nil {
+		in.mu.Lock()
+		in.running = true
+		in.mu.Unlock()
+	}
+	in.startMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < in.config.Workers; i++ {
+		in.wg.Add(1)
+		go in.worker(ctx)
+	}
+
+	in.wg.Add(1)
+	go func() {
+		defer in.wg.Done()
+		for _, entry := range pending {
+			// Entries received since Start began are already queued.
+			in.mu.Lock()
+			skip := in.queued[entry.ID]
+			if !skip {
+				in.inFlight[entry.Key] = true
+				in.queued[entry.ID] = true
+				in.reserved++
+			}
+			in.mu.Unlock()
+			if skip {
+				continue
+			}
+
+			select {
+			case in.queue <- entry:
+			case <-ctx.Done():
+				in.mu.Lock()
+				delete(in.queued, entry.ID)
+				in.reserved--
+				in.mu.Unlock()
+				return
+			}
+		}
+	}()
+
+	in.wg.Wait()
+
+	in.mu.Lock()
+	in.running = false
+	for len(in.queue) > 0 {
+		entry := <-in.queue
+		delete(in.queued, entry.ID)
+		in.reserved--
+	}
+	in.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// ServeHTTP journals a delivery and acknowledges it. While Start is running
+// the entry is queued at once, or 503 is returned if the queue is full;
+// otherwise it waits in the journal for Start.
+func (in *WebhookInbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, status := readWebhookRequest(r, in.config.Verifier)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	payload, err := ParseWebhookPayload(body)
+	if err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	key := in.config.DedupeKey(payload)
+
+	in.startMu.RLock()
+	defer in.startMu.RUnlock()
+
+	in.mu.Lock()
+	duplicate := in.inFlight[key] || in.done[key]
+	running := in.running
+	full := running && in.reserved >= cap(in.queue)
+	if !duplicate && !full {
+		in.inFlight[key] = true
+		if running {
+			in.reserved++
+		}
+	}
+	in.mu.Unlock()
+
+	if duplicate {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if full {
+		http.Error(w, "Webhook inbox is full", http.StatusServiceUnavailable)
+		return
+	}
+
+	entry := &InboxEntry{
+		ID:         randomID(),
+		Key:        key,
+		ReceivedAt: time.Now().UTC(),
+		Payload:    json.RawMessage(body),
+	}
+	if err := in.config.Journal.Append(entry); err != nil {
+		in.mu.Lock()
+		delete(in.inFlight, key)
+		if running {
+			in.reserved--
+		}
+		in.mu.Unlock()
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if running {
+		in.mu.Lock()
+		in.queued[entry.ID] = true
+		in.mu.Unlock()
+		// A slot was reserved above, so this does not block.
+		in.queue <- entry
+	}
+}
+
+func (in *WebhookInbox) worker(ctx context.Context) {
+	defer in.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-in.queue:
+			in.mu.Lock()
+			in.reserved--
+			in.mu.Unlock()
+			in.process(ctx, entry)
+		}
+	}
+}
+
+func (in *WebhookInbox) process(ctx context.Context, entry *InboxEntry) {
+	payload, err := ParseWebhookPayload(entry.Payload)
+	for err == nil {
+		entry.Attempts++
+		if err = in.config.Handler(ctx, payload); err == nil {
+			break
+		}
+		if in.config.OnError != nil {
+			in.config.OnError(entry, err)
+		}
+		if entry.Attempts >= in.config.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(in.backoff(entry.Attempts)):
+			err = nil
+		case <-ctx.Done():
+			// Left pending in the journal for the next Start.
+			in.mu.Lock()
+			delete(in.queued, entry.ID)
+			in.mu.Unlock()
+			return
+		}
+	}
+
+	if err != nil {
+		entry.LastError = err.Error()
+		if markErr := in.config.Journal.MarkDead(entry); markErr != nil && in.config.OnError != nil {
+			in.config.OnError(entry, markErr)
+		}
+	} else if markErr := in.config.Journal.MarkDone(entry); markErr != nil && in.config.OnError != nil {
+		in.config.OnError(entry, markErr)
+	}
+
+	in.mu.Lock()
+	delete(in.queued, entry.ID)
+	delete(in.inFlight, entry.Key)
+	in.rememberDoneLocked(entry.Key)
+	in.mu.Unlock()
+}
+
+func (in *WebhookInbox) backoff(attempt int) time.Duration {
+	d := in.config.Backoff << uint(attempt-1)
+	if d <= 0 || d > in.config.MaxBackoff {
+		d = in.config.MaxBackoff
+	}
+	return d
+}
+
+func (in *WebhookInbox) rememberDoneLocked(key string) {
+	if in.done[key] {
+		return
+	}
+	in.done[key] = true
+	in.order = append(in.order, key)
+	for len(in.order) > in.config.DedupeSize {
+		delete(in.done, in.order[0])
+		in.order = in.order[1:]
+	}
+}
+
+// WebhookDedupeKey identifies a delivery by its event_id when present, and
+// otherwise by task ID and event type. Repeated "ask" stops for one task are
+// told apart by their message.
+func WebhookDedupeKey(payload *WebhookPayload) string {
+	if payload.EventID != "" {
+		return payload.EventID
+	}
+
+	detail := payload.TaskDetail
+
+	taskID, _ := detail["task_id"].(string)
+	key := taskID + ":" + payload.EventType
+	if IsTaskAskingForInput(payload) {
+		message, _ := detail["message"].(string)
+		sum := sha256.Sum256([]byte(message))
+		key += ":ask:" + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
+
+func randomID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+type journalRecord struct {
+	Op    string      `json:"op"`
+	Entry *InboxEntry `json:"entry,omitempty"`
+	ID    string      `json:"id,omitempty"`
+	Key   string      `json:"key,omitempty"`
+}
+
+// FileJournal is an append-only WebhookJournal stored in a directory:
+// journal.log records received and completed entries, and dead-letter.log
+// collects entries that exhausted their retries.
+type FileJournal struct {
+	mu      sync.Mutex
+	dir     string
+	journal *os.File
+}
+
+func NewFileJournal(dir string) (*FileJournal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "journal.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileJournal{dir: dir, journal: f}, nil
+}
+
+func (j *FileJournal) Append(entry *InboxEntry) error {
+	return j.write(journalRecord{Op: "append", Entry: entry})
+}
+
+func (j *FileJournal) MarkDone(entry *InboxEntry) error {
+	return j.write(journalRecord{Op: "done", ID: entry.ID, Key: entry.Key})
+}
+
+func (j *FileJournal) MarkDead(entry *InboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	f, err := os.OpenFile(filepath.Join(j.dir, "dead-letter.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err == nil {
+		_, err = f.Write(append(data, '\n'))
+		if syncErr := f.Sync(); err == nil {
+			err = syncErr
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return j.write(journalRecord{Op: "dead", ID: entry.ID, Key: entry.Key})
+}
+
+func (j *FileJournal) Pending() ([]*InboxEntry, error) {
+	entries, _, err := j.replay()
+	return entries, err
+}
+
+func (j *FileJournal) Done() ([]string, error) {
+	_, keys, err := j.replay()
+	return keys, err
+}
+
+// DeadLetters returns the entries written to dead-letter.log.
+func (j *FileJournal) DeadLetters() ([]*InboxEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entries []*InboxEntry
+	err := readJSONLines(filepath.Join(j.dir, "dead-letter.log"), func(line []byte) error {
+		var entry InboxEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, &entry)
+		return nil
+	})
+	return entries, err
+}
+
+// Compact rewrites journal.log so that it only holds pending entries and the
+// keys of the last DefaultInboxDedupeSize completed ones.
+func (j *FileJournal) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pending, done, err := j.replayLocked()
+	if err != nil {
+		return err
+	}
+
+	var buf []byte
+	for _, key := range recentKeys(done, DefaultInboxDedupeSize) {
+		line, err := json.Marshal(journalRecord{Op: "done", Key: key})
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	for _, entry := range pending {
+		line, err := json.Marshal(journalRecord{Op: "append", Entry: entry})
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	path := filepath.Join(j.dir, "journal.log")
+	if err := writeFileDurable(path, buf); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.journal.Close()
+	j.journal = f
+	return nil
+}
+
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.journal.Close()
+}
+
+func (j *FileJournal) write(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.journal.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return j.journal.Sync()
+}
+
+func (j *FileJournal) replay() ([]*InboxEntry, []string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.replayLocked()
+}
+
+func (j *FileJournal) replayLocked() ([]*InboxEntry, []string, error) {
+	var order []string
+	pending := make(map[string]*InboxEntry)
+	var done []string
+
+	err := readJSONLines(filepath.Join(j.dir, "journal.log"), func(line []byte) error {
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// A torn final write after a crash is expected; skip it.
+			return nil
+		}
+		switch record.Op {
+		case "append":
+			if record.Entry != nil {
+				pending[record.Entry.ID] = record.Entry
+				order = append(order, record.Entry.ID)
+			}
+		case "done", "dead":
+			delete(pending, record.ID)
+			done = append(done, record.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]*InboxEntry, 0, len(pending))
+	for _, id := range order {
+		if entry, ok := pending[id]; ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, done, nil
+}
+
+// recentKeys returns the last n distinct keys, oldest first.
+func recentKeys(keys []string, n int) []string {
+	seen := make(map[string]bool)
+	var recent []string
+	for i := len(keys) - 1; i >= 0 && len(recent) < n; i-- {
+		if key := keys[i]; key != "" && !seen[key] {
+			seen[key] = true
+			recent = append(recent, key)
+		}
+	}
+	for i, k := 0, len(recent)-1; i < k; i, k = i+1, k-1 {
+		recent[i], recent[k] = recent[k], recent[i]
+	}
+	return recent
+}
+
+func readJSONLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxWebhookBodyBytes*2)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// MemoryJournal is a WebhookJournal for tests and single-process use. It
+// remembers the keys of the last DefaultInboxDedupeSize completed entries.
+type MemoryJournal struct {
+	mu      sync.Mutex
+	pending []*InboxEntry
+	done    []string
+	dead    []*InboxEntry
+}
+
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{}
+}
+
+func (j *MemoryJournal) Append(entry *InboxEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cp := *entry
+	j.pending = append(j.pending, &cp)
+	return nil
+}
+
+func (j *MemoryJournal) MarkDone(entry *InboxEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.remove(entry.ID)
+	j.done = append(j.done, entry.Key)
+	if n := len(j.done) - DefaultInboxDedupeSize; n > 0 {
+		j.done = append([]string(nil), j.done[n:]...)
+	}
+	return nil
+}
+
+func (j *MemoryJournal) MarkDead(entry *InboxEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.remove(entry.ID)
+	cp := *entry
+	j.dead = append(j.dead, &cp)
+	return nil
+}
+
+func (j *MemoryJournal) Pending() ([]*InboxEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*InboxEntry(nil), j.pending...), nil
+}
+
+func (j *MemoryJournal) Done() ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.done...), nil
+}
+
+func (j *MemoryJournal) DeadLetters() []*InboxEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*InboxEntry(nil), j.dead...)
+}
+
+func (j *MemoryJournal) remove(id string) {
+	for i, entry := range j.pending {
+		if entry.ID == id {
+			j.pending = append(j.pending[:i:i], j.pending[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postInbox(t *testing.T, inbox http.Handler, body string) int {
+	rec := httptest.NewRecorder()
+	inbox.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook", strings.NewReader(body)))
+	return rec.Code
+}
+
+func TestWebhookDedupeKey(t *testing.T) {
+	assert.Equal(t, "evt_1", WebhookDedupeKey(&WebhookPayload{EventID: "evt_1", EventType: WebhookEventTaskCreated}))
+	assert.Equal(t, "task_1:task_created", WebhookDedupeKey(&WebhookPayload{
+		EventType: WebhookEventTaskCreated, TaskDetail: map[string]interface{}{"task_id": "task_1"},
+	}))
+
+	ask := func(message string) string {
+		return WebhookDedupeKey(&WebhookPayload{EventType: WebhookEventTaskStopped, TaskDetail: map[string]interface{}{
+			"task_id": "task_1", "stop_reason": StopReasonAsk, "message": message,
+		}})
+	}
+	assert.NotEqual(t, ask("Which year?"), ask("Which region?"))
+}
+
+func TestWebhookInboxRetriesAndDeduplicates(t *testing.T) {
+	journal := NewMemoryJournal()
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	processed := make(chan string, 10)
+
+	inbox, err := NewWebhookInbox(WebhookInboxConfig{
+		Journal: journal,
+		Backoff: time.Millisecond,
+		Handler: func(ctx context.Context, payload *WebhookPayload) error {
+			taskID := payload.TaskDetail["task_id"].(string)
+			mu.Lock()
+			attempts[taskID]++
+			n := attempts[taskID]
+			mu.Unlock()
+			if n < 3 {
+				return errors.New("temporary failure")
+			}
+			processed <- taskID
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go inbox.Start(ctx)
+
+	body := `{"event_type":"task_created","task_detail":{"task_id":"task_1"}}`
+	assert.Equal(t, http.StatusOK, postInbox(t, inbox, body))
+	assert.Equal(t, http.StatusOK, postInbox(t, inbox, body))
+
+	select {
+	case id := <-processed:
+		assert.Equal(t, "task_1", id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("entry was not processed")
+	}
+
+	assert.Equal(t, http.StatusOK, postInbox(t, inbox, body))
+	assert.Equal(t, http.StatusBadRequest, postInbox(t, inbox, `{}`))
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 3, attempts["task_1"])
+	mu.Unlock()
+
+	pending, _ := journal.Pending()
+	assert.Empty(t, pending)
+}
+
+func TestWebhookInboxDeadLetter(t *testing.T) {
+	journal := NewMemoryJournal()
+	inbox, err := NewWebhookInbox(WebhookInboxConfig{
+		Journal:     journal,
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		Handler: func(ctx context.Context, payload *WebhookPayload) error {
+			return errors.New("permanent failure")
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go inbox.Start(ctx)
+
+	postInbox(t, inbox, `{"event_type":"task_created","task_detail":{"task_id":"task_1"}}`)
+
+	require.Eventually(t, func() bool { return len(journal.DeadLetters()) == 1 }, 2*time.Second, 5*time.Millisecond)
+	dead := journal.DeadLetters()[0]
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, "permanent failure", dead.LastError)
+}
+
+func TestFileJournalReplay(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := NewFileJournal(dir)
+	require.NoError(t, err)
+
+	blocked, err := NewWebhookInbox(WebhookInboxConfig{
+		Journal: journal,
+		Handler: func(ctx context.Context, payload *WebhookPayload) error { return nil },
+	})
+	require.NoError(t, err)
+
+	// Deliveries are journaled and acknowledged without any worker running,
+	// as if the process crashed right after answering 200.
+	postInbox(t, blocked, `{"event_type":"task_created","task_detail":{"task_id":"task_1"}}`)
+	postInbox(t, blocked, `{"event_type":"task_created","task_detail":{"task_id":"task_2"}}`)
+	require.NoError(t, journal.Close())
+
+	journal, err = NewFileJournal(dir)
+	require.NoError(t, err)
+	defer journal.Close()
+
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	processed := make(chan string, 2)
+	inbox, err := NewWebhookInbox(WebhookInboxConfig{
+		Journal: journal,
+		Handler: func(ctx context.Context, payload *WebhookPayload) error {
+			processed <- payload.TaskDetail["task_id"].(string)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go inbox.Start(ctx)
+
+	assert.Equal(t, "task_1", <-processed)
+	assert.Equal(t, "task_2", <-processed)
+
+	require.Eventually(t, func() bool {
+		pending, _ := journal.Pending()
+		return len(pending) == 0
+	}, 2*time.Second, 5*time.Millisecond)
+
+	require.NoError(t, journal.Append(&InboxEntry{ID: "in_flight", Key: "task_3:task_created"}))
+	require.NoError(t, journal.Compact())
+	keys, err := journal.Done()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task_1:task_created", "task_2:task_created"}, keys)
+	pending, err = journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "in_flight", pending[0].ID)
+
+	assert.Equal(t, http.StatusOK, postInbox(t, inbox, `{"event_type":"task_created","task_detail":{"task_id":"task_1"}}`))
+
+	// After a restart the compacted journal still de-duplicates redeliveries.
+	cancel()
+	require.NoError(t, journal.Close())
+	journal, err = NewFileJournal(dir)
+	require.NoError(t, err)
+	defer journal.Close()
+
+	restarted, err := NewWebhookInbox(WebhookInboxConfig{
+		Journal: journal,
+		Handler: func(ctx context.Context, payload *WebhookPayload) error { return nil },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, postInbox(t, restarted, `{"event_type":"task_created","task_detail":{"task_id":"task_2"}}`))
+	pending, err = journal.Pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, 1, "duplicate is not journaled again")
+}
+
+func TestRecentKeys(t *testing.T) {
+	assert.Equal(t, []string{"b", "a", "c"}, recentKeys([]string{"a", "b", "", "a", "c"}, 5))
+	assert.Equal(t, []string{"a", "c"}, recentKeys([]string{"a", "b", "a", "c"}, 2))
+}
+
+func TestWebhookInboxFullQueue(t *testing.T) {
+	journal := NewMemoryJournal()
+	inbox, err := NewWebhookInbox(WebhookInboxConfig{
+		Journal: journal,
+		Handler: func(ctx context.Context, payload *WebhookPayload) error { return nil },
+	})
+	require.NoError(t, err)
+
+	// Not running: deliveries wait in the journal instead of the queue.
+	assert.Equal(t, http.StatusOK, postInbox(t, inbox, `{"event_id":"evt_1","event_type":"task_created"}`))
+	assert.Len(t, inbox.queue, 0)
+
+	inbox.mu.Lock()
+	inbox.running = true
+	inbox.reserved = cap(inbox.queue)
+	inbox.mu.Unlock()
+	assert.Equal(t, http.StatusServiceUnavailable, postInbox(t, inbox, `{"event_id":"evt_2","event_type":"task_created"}`))
+	assert.Equal(t, http.StatusOK, postInbox(t, inbox, `{"event_id":"evt_1","event_type":"task_created"}`), "duplicate")
+
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "evt_1", pending[0].Key)
+}
+
+func TestMemoryJournalPrunesDoneKeys(t *testing.T) {
+	journal := NewMemoryJournal()
+	for i := 0; i < DefaultInboxDedupeSize+5; i++ {
+		require.NoError(t, journal.MarkDone(&InboxEntry{ID: "id", Key: fmt.Sprintf("evt_%d", i)}))
+	}
+	keys, err := journal.Done()
+	require.NoError(t, err)
+	assert.Len(t, keys, DefaultInboxDedupeSize)
+	assert.Equal(t, "evt_5", keys[0])
+}
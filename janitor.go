@@ -0,0 +1,216 @@
+package manusai
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultJanitorConcurrency = 4
+
+type FileJanitorConfig struct {
+	// Retention selects files created at least this long ago.
+	Retention time.Duration
+	// Patterns selects files whose name matches one of these globs. When
+	// both Retention and Patterns are set, a file must satisfy both, so that
+	// a pattern cannot delete files younger than the retention period.
+	Patterns []string
+	// TaskStore protects files attached to tasks that have not completed or
+	// failed. It is required unless IgnoreTasks is set.
+	TaskStore TaskStore
+	// IgnoreTasks allows running without a TaskStore, deleting selected
+	// files even if a running task still uses them.
+	IgnoreTasks bool
+	Concurrency int
+	DryRun      bool
+	Now         func() time.Time
+}
+
+type JanitorSkip struct {
+	File   FileDetail
+	Reason string
+}
+
+type JanitorFailure struct {
+	File FileDetail
+	Err  error
+}
+
+type JanitorReport struct {
+	DryRun  bool
+	Scanned int
+	// Deleted lists the files removed, or in a dry run, the files that
+	// would have been removed.
+	Deleted []FileDetail
+	Skipped []JanitorSkip
+	Failed  []JanitorFailure
+}
+
+func (r *JanitorReport) Summary() string {
+	verb := "deleted"
+	if r.DryRun {
+		verb = "would delete"
+	}
+
+	var bytes int64
+	for _, f := range r.Deleted {
+		bytes += f.SizeBytes
+	}
+	return fmt.Sprintf("scanned %d files, %s %d (%d bytes), skipped %d, %d failed",
+		r.Scanned, verb, len(r.Deleted), bytes, len(r.Skipped), len(r.Failed))
+}
+
+// FileJanitor deletes uploaded files selected by retention period and name
+// patterns, keeping files still attached to active tasks.
+type FileJanitor struct {
+	client *Client
+	config FileJanitorConfig
+}
+
+func NewFileJanitor(client *Client, config FileJanitorConfig) (*FileJanitor, error) {
+	if config.Retention <= 0 && len(config.Patterns) == 0 {
+		return nil, &ValidationError{Message: "File janitor needs a retention period or name patterns"}
+	}
+	if config.TaskStore == nil && !config.IgnoreTasks {
+		return nil, &ValidationError{Message: "File janitor needs a task store to keep files used by active tasks, or IgnoreTasks"}
+	}
+	for _, pattern := range config.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, &ValidationError{Message: fmt.Sprintf("Invalid file pattern %q", pattern), Err: err}
+		}
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultJanitorConcurrency
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &FileJanitor{client: client, config: config}, nil
+}
+
+func (j *FileJanitor) Run(ctx context.Context) (*JanitorReport, error) {
+	files, err := j.client.listFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse, err := j.filesInUse()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &JanitorReport{DryRun: j.config.DryRun, Scanned: len(files.Data)}
+	var candidates []FileDetail
+	for _, file := range files.Data {
+		selected, reason := j.selects(file)
+		if !selected {
+			if reason != "" {
+				report.Skipped = append(report.Skipped, JanitorSkip{File: file, Reason: reason})
+			}
+			continue
+		}
+		if taskID, ok := inUse[file.ID]; ok {
+			report.Skipped = append(report.Skipped, JanitorSkip{File: file, Reason: "attached to active task " + taskID})
+			continue
+		}
+		candidates = append(candidates, file)
+	}
+
+	if j.config.DryRun {
+		report.Deleted = candidates
+		return report, nil
+	}
+
+	var mu sync.Mutex
+	_ = runParallel(ctx, len(candidates), j.config.Concurrency, func(i int) error {
+		file := candidates[i]
+		_, err := j.client.deleteFile(ctx, file.ID)
+
+		if err != nil && ctx.Err() != nil {
+			// Interrupted: the file is neither deleted nor a failure.
+			return ctx.Err()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && !isNotFound(err) {
+			report.Failed = append(report.Failed, JanitorFailure{File: file, Err: err})
+		} else {
+			report.Deleted = append(report.Deleted, file)
+		}
+		return nil
+	})
+
+	sort.Slice(report.Deleted, func(a, b int) bool { return report.Deleted[a].ID < report.Deleted[b].ID })
+	sort.Slice(report.Failed, func(a, b int) bool { return report.Failed[a].File.ID < report.Failed[b].File.ID })
+
+	return report, ctx.Err()
+}
+
+// selects reports whether file matches the retention and name rules. A
+// non-empty reason means the file could not be judged and should be listed
+// as skipped.
+func (j *FileJanitor) selects(file FileDetail) (bool, string) {
+	if len(j.config.Patterns) > 0 && !matchFilePatterns(j.config.Patterns, file.Filename) {
+		return false, ""
+	}
+
+	if j.config.Retention > 0 {
+		created, ok := parseFileTime(file.CreatedAt)
+		if !ok {
+			return false, "unknown creation time"
+		}
+		if j.config.Now().Sub(created) < j.config.Retention {
+			return false, ""
+		}
+	}
+
+	return true, ""
+}
+
+func (j *FileJanitor) filesInUse() (map[string]string, error) {
+	inUse := make(map[string]string)
+	if j.config.TaskStore == nil {
+		return inUse, nil
+	}
+
+	records, err := j.config.TaskStore.Find(TaskQuery{})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.Status == TaskStatusCompleted || record.Status == TaskStatusFailed {
+			continue
+		}
+		for _, fileID := range record.FileIDs {
+			inUse[fileID] = record.TaskID
+		}
+	}
+	return inUse, nil
+}
+
+func matchFilePatterns(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFileTime accepts RFC 3339 timestamps and Unix seconds.
+func parseFileTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,157 @@
+package manusai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJanitorServer(t *testing.T, files []FileDetail) (*Client, func() []string) {
+	var mu sync.Mutex
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v1/files":
+			json.NewEncoder(w).Encode(FileListResponse{Data: files})
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/files/"):
+			id := strings.TrimPrefix(r.URL.Path, "/v1/files/")
+			if id == "file_broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			mu.Lock()
+			deleted = append(deleted, id)
+			mu.Unlock()
+			json.NewEncoder(w).Encode(DeleteResponse{Deleted: true})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Strings(deleted)
+		return append([]string(nil), deleted...)
+	}
+}
+
+func TestFileJanitor(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	files := []FileDetail{
+		{ID: "file_old", Filename: "old.csv", CreatedAt: now.Add(-72 * time.Hour).Format(time.RFC3339)},
+		{ID: "file_new", Filename: "new.csv", CreatedAt: now.Add(-time.Hour).Format(time.RFC3339)},
+		{ID: "file_unix", Filename: "unix.csv", CreatedAt: "1700000000"},
+		{ID: "file_active", Filename: "active.csv", CreatedAt: now.Add(-72 * time.Hour).Format(time.RFC3339)},
+		{ID: "file_done", Filename: "done.csv", CreatedAt: now.Add(-72 * time.Hour).Format(time.RFC3339)},
+		{ID: "file_odd", Filename: "odd.csv", CreatedAt: "yesterday"},
+	}
+	client, deleted := newJanitorServer(t, files)
+
+	store := NewMemoryTaskStore()
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_1", Status: TaskStatusRunning, FileIDs: []string{"file_active"}}))
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_2", Status: TaskStatusCompleted, FileIDs: []string{"file_done"}}))
+
+	janitor, err := NewFileJanitor(client, FileJanitorConfig{
+		Retention: 48 * time.Hour,
+		TaskStore: store,
+		Now:       func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	report, err := janitor.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"file_done", "file_old", "file_unix"}, deleted())
+	assert.Equal(t, 6, report.Scanned)
+	assert.Len(t, report.Deleted, 3)
+	require.Len(t, report.Skipped, 2)
+	assert.Equal(t, "file_active", report.Skipped[0].File.ID)
+	assert.Contains(t, report.Skipped[0].Reason, "task_1")
+	assert.Equal(t, "file_odd", report.Skipped[1].File.ID)
+	assert.Contains(t, report.Summary(), "deleted 3")
+}
+
+func TestFileJanitorPatternsAndDryRun(t *testing.T) {
+	files := []FileDetail{
+		{ID: "file_a", Filename: "scratch-1.tmp"},
+		{ID: "file_b", Filename: "report.pdf"},
+	}
+	client, deleted := newJanitorServer(t, files)
+
+	janitor, err := NewFileJanitor(client, FileJanitorConfig{Patterns: []string{"*.tmp"}, IgnoreTasks: true, DryRun: true})
+	require.NoError(t, err)
+
+	report, err := janitor.Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, deleted())
+	require.Len(t, report.Deleted, 1)
+	assert.Equal(t, "file_a", report.Deleted[0].ID)
+	assert.Contains(t, report.Summary(), "would delete 1")
+}
+
+func TestFileJanitorFailures(t *testing.T) {
+	files := []FileDetail{
+		{ID: "file_ok", Filename: "a.tmp"},
+		{ID: "file_broken", Filename: "b.tmp"},
+	}
+	client, deleted := newJanitorServer(t, files)
+
+	janitor, err := NewFileJanitor(client, FileJanitorConfig{Patterns: []string{"*.tmp"}, IgnoreTasks: true, Concurrency: 1})
+	require.NoError(t, err)
+
+	report, err := janitor.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file_ok"}, deleted())
+	require.Len(t, report.Failed, 1)
+	assert.Equal(t, "file_broken", report.Failed[0].File.ID)
+}
+
+func TestNewFileJanitorValidation(t *testing.T) {
+	client, _ := newJanitorServer(t, nil)
+
+	_, err := NewFileJanitor(client, FileJanitorConfig{IgnoreTasks: true})
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = NewFileJanitor(client, FileJanitorConfig{Patterns: []string{"[bad"}, IgnoreTasks: true})
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = NewFileJanitor(client, FileJanitorConfig{Patterns: []string{"*.tmp"}})
+	assert.IsType(t, &ValidationError{}, err, "needs a task store or IgnoreTasks")
+}
+
+func TestFileJanitorCancelled(t *testing.T) {
+	client, deleted := newJanitorServer(t, []FileDetail{{ID: "file_a", Filename: "a.tmp"}})
+
+	janitor, err := NewFileJanitor(client, FileJanitorConfig{Patterns: []string{"*.tmp"}, IgnoreTasks: true})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = janitor.Run(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, deleted())
+}
+
+func TestAttachmentFileIDs(t *testing.T) {
+	ids := attachmentFileIDs([]interface{}{
+		NewAttachmentFromFileID("file_map"),
+		TaskAttachment{Type: "file_id", FileID: "file_struct"},
+		&TaskAttachment{Type: "file_id", FileID: "file_ptr"},
+		(*TaskAttachment)(nil),
+		NewAttachmentFromURL("https://example.com/a.pdf"),
+	})
+	assert.Equal(t, []string{"file_map", "file_struct", "file_ptr"}, ids)
+}
@@ -0,0 +1,633 @@
+package manusai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPoolCooldown = time.Minute
+
+	// maxPoolCursors bounds the GetTasks page cursors a pool remembers.
+	maxPoolCursors = 1024
+)
+
+// API is the set of operations shared by Client and ClientPool.
+type API interface {
+	CreateTask(prompt string, options *TaskOptions) (*TaskResponse, error)
+	GetTasks(filters *TaskFilters) (*TaskListResponse, error)
+	GetTask(taskID string) (*TaskDetail, error)
+	UpdateTask(taskID string, updates *TaskUpdate) (*TaskDetail, error)
+	DeleteTask(taskID string) (*DeleteResponse, error)
+	CreateFile(filename string) (*FileResponse, error)
+	UploadFileContent(uploadURL string, fileContent []byte, contentType string) error
+	ListFiles() (*FileListResponse, error)
+	GetFile(fileID string) (*FileDetail, error)
+	DeleteFile(fileID string) (*DeleteResponse, error)
+	CreateWebhook(webhook *WebhookConfig) (*WebhookResponse, error)
+	DeleteWebhook(webhookID string) error
+	ObserveWebhook(payload *WebhookPayload)
+}
+
+var (
+	_ API = (*Client)(nil)
+	_ API = (*ClientPool)(nil)
+)
+
+type PoolStrategy string
+
+const (
+	PoolRoundRobin   PoolStrategy = "round_robin"
+	PoolLeastCredits PoolStrategy = "least_credits"
+	PoolWeighted     PoolStrategy = "weighted"
+)
+
+type PoolMember struct {
+	Name   string
+	Client *Client
+	// Weight is used by PoolWeighted; values below 1 count as 1.
+	Weight int
+}
+
+type ClientPoolConfig struct {
+	Members  []PoolMember
+	Strategy PoolStrategy
+	// Cooldown is how long a member is skipped after a 429 or quota error.
+	Cooldown time.Duration
+	Now      func() time.Time
+}
+
+type PoolMemberStats struct {
+	Name          string
+	Tasks         int
+	CreditsUsed   float64
+	CooldownUntil time.Time
+}
+
+// ClientPool spreads work across several Manus accounts. New tasks and files
+// go to a member chosen by the strategy; requests for an existing task or
+// file go to the member that created it.
+type ClientPool struct {
+	config  ClientPoolConfig
+	members []*poolMember
+
+	mu          sync.Mutex
+	next        int
+	tasks       map[string]*poolMember
+	files       map[string]*poolMember
+	webhooks    map[string][]poolWebhook
+	cursors     map[string]map[*poolMember]string
+	cursorOrder []string
+}
+
+type poolMember struct {
+	PoolMember
+	current       int
+	tasks         int
+	credits       map[string]float64
+	cooldownUntil time.Time
+}
+
+type poolWebhook struct {
+	member *poolMember
+	id     string
+}
+
+func NewClientPool(config ClientPoolConfig) (*ClientPool, error) {
+	if len(config.Members) == 0 {
+		return nil, &ValidationError{Message: "Client pool needs at least one member"}
+	}
+	switch config.Strategy {
+	case "":
+		config.Strategy = PoolRoundRobin
+	case PoolRoundRobin, PoolLeastCredits, PoolWeighted:
+	default:
+		return nil, &ValidationError{Message: fmt.Sprintf("Unknown pool strategy %q", config.Strategy)}
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = DefaultPoolCooldown
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	pool := &ClientPool{
+		config:   config,
+		tasks:    make(map[string]*poolMember),
+		files:    make(map[string]*poolMember),
+		webhooks: make(map[string][]poolWebhook),
+		cursors:  make(map[string]map[*poolMember]string),
+	}
+	for i, m := range config.Members {
+		if m.Client == nil {
+			return nil, &ValidationError{Message: fmt.Sprintf("Pool member %d has no client", i)}
+		}
+		if m.Name == "" {
+			m.Name = fmt.Sprintf("member-%d", i)
+		}
+		if m.Weight < 1 {
+			m.Weight = 1
+		}
+		pool.members = append(pool.members, &poolMember{PoolMember: m, credits: make(map[string]float64)})
+	}
+
+	return pool, nil
+}
+
+// Owner returns the name of the member that owns a task, if known.
+func (p *ClientPool) Owner(taskID string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m, ok := p.tasks[taskID]; ok {
+		return m.Name, true
+	}
+	return "", false
+}
+
+func (p *ClientPool) Stats() []PoolMemberStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]PoolMemberStats, len(p.members))
+	for i, m := range p.members {
+		stats[i] = PoolMemberStats{
+			Name:          m.Name,
+			Tasks:         m.tasks,
+			CreditsUsed:   m.creditsUsed(),
+			CooldownUntil: m.cooldownUntil,
+		}
+	}
+	return stats
+}
+
+// CreateTask sends the task to the member chosen by the strategy, moving on
+// to the next member when one is rate limited or out of quota. Tasks that
+// attach files uploaded through the pool go to the account owning the files.
+func (p *ClientPool) CreateTask(prompt string, options *TaskOptions) (*TaskResponse, error) {
+	candidates := p.candidates()
+	if owner := p.attachmentOwner(options); owner != nil {
+		candidates = []*poolMember{owner}
+	}
+
+	var lastErr error
+	for _, m := range candidates {
+		resp, err := m.Client.CreateTask(prompt, options)
+		if err == nil {
+			p.mu.Lock()
+			p.tasks[resp.TaskID] = m
+			m.tasks++
+			p.mu.Unlock()
+			return resp, nil
+		}
+		if !isQuotaError(err) {
+			return nil, err
+		}
+		p.coolDown(m)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// GetTasks merges a page from every member. To get the next page, pass the
+// ID of the last task as filters.After, as with a single client: the pool
+// remembers where each member's page ended and continues every member that
+// has more. An After that does not end a page returned by this pool is
+// rejected.
+func (p *ClientPool) GetTasks(filters *TaskFilters) (*TaskListResponse, error) {
+	var query TaskFilters
+	if filters != nil {
+		query = *filters
+	}
+
+	pages := make(map[*poolMember]string)
+	if query.After != "" {
+		p.mu.Lock()
+		cursor, ok := p.cursors[query.After]
+		p.mu.Unlock()
+		if !ok {
+			return nil, &ValidationError{Message: fmt.Sprintf("Unknown pool cursor %q: use the last task ID of a page returned by this pool", query.After)}
+		}
+		pages = cursor
+	} else {
+		for _, m := range p.members {
+			pages[m] = ""
+		}
+	}
+
+	merged := &TaskListResponse{}
+	next := make(map[*poolMember]string)
+	for _, m := range p.members {
+		after, ok := pages[m]
+		if !ok {
+			continue
+		}
+		memberQuery := query
+		memberQuery.After = after
+		result, err := m.Client.GetTasks(&memberQuery)
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		for _, summary := range result.Data {
+			p.tasks[summary.ID] = m
+		}
+		p.mu.Unlock()
+		merged.Data = append(merged.Data, result.Data...)
+		if result.HasMore && len(result.Data) > 0 {
+			next[m] = result.Data[len(result.Data)-1].ID
+		}
+	}
+
+	merged.HasMore = len(next) > 0
+	if merged.HasMore && len(merged.Data) > 0 {
+		p.saveCursor(merged.Data[len(merged.Data)-1].ID, next)
+	}
+	return merged, nil
+}
+
+// saveCursor remembers where each member's page ended, keyed by the last
+// task ID of the merged page.
+func (p *ClientPool) saveCursor(last string, next map[*poolMember]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.cursors[last]; !ok {
+		p.cursorOrder = append(p.cursorOrder, last)
+	}
+	p.cursors[last] = next
+	for len(p.cursorOrder) > maxPoolCursors {
+		delete(p.cursors, p.cursorOrder[0])
+		p.cursorOrder = p.cursorOrder[1:]
+	}
+}
+
+func (p *ClientPool) GetTask(taskID string) (*TaskDetail, error) {
+	var detail *TaskDetail
+	err := p.routeTask(taskID, func(c *Client) error {
+		var err error
+		detail, err = c.GetTask(taskID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.recordCredits(detail)
+	return detail, nil
+}
+
+func (p *ClientPool) UpdateTask(taskID string, updates *TaskUpdate) (*TaskDetail, error) {
+	var detail *TaskDetail
+	err := p.routeTask(taskID, func(c *Client) error {
+		var err error
+		detail, err = c.UpdateTask(taskID, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.recordCredits(detail)
+	return detail, nil
+}
+
+func (p *ClientPool) DeleteTask(taskID string) (*DeleteResponse, error) {
+	var result *DeleteResponse
+	err := p.routeTask(taskID, func(c *Client) error {
+		var err error
+		result, err = c.DeleteTask(taskID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	delete(p.tasks, taskID)
+	p.mu.Unlock()
+	return result, nil
+}
+
+func (p *ClientPool) CreateFile(filename string) (*FileResponse, error) {
+	var lastErr error
+	for _, m := range p.candidates() {
+		resp, err := m.Client.CreateFile(filename)
+		if err == nil {
+			p.mu.Lock()
+			p.files[resp.ID] = m
+			p.mu.Unlock()
+			return resp, nil
+		}
+		if !isQuotaError(err) {
+			return nil, err
+		}
+		p.coolDown(m)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// UploadFileContent uploads to a pre-signed URL, which needs no account.
+func (p *ClientPool) UploadFileContent(uploadURL string, fileContent []byte, contentType string) error {
+	return p.members[0].Client.UploadFileContent(uploadURL, fileContent, contentType)
+}
+
+func (p *ClientPool) ListFiles() (*FileListResponse, error) {
+	merged := &FileListResponse{}
+	for _, m := range p.members {
+		result, err := m.Client.ListFiles()
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		for _, file := range result.Data {
+			p.files[file.ID] = m
+		}
+		p.mu.Unlock()
+		merged.Data = append(merged.Data, result.Data...)
+	}
+	return merged, nil
+}
+
+func (p *ClientPool) GetFile(fileID string) (*FileDetail, error) {
+	var detail *FileDetail
+	err := p.routeFile(fileID, func(c *Client) error {
+		var err error
+		detail, err = c.GetFile(fileID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+func (p *ClientPool) DeleteFile(fileID string) (*DeleteResponse, error) {
+	var result *DeleteResponse
+	err := p.routeFile(fileID, func(c *Client) error {
+		var err error
+		result, err = c.DeleteFile(fileID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	delete(p.files, fileID)
+	p.mu.Unlock()
+	return result, nil
+}
+
+// CreateWebhook registers the webhook with every member, since each account
+// only reports its own tasks. The returned ID removes all of them through
+// DeleteWebhook.
+func (p *ClientPool) CreateWebhook(webhook *WebhookConfig) (*WebhookResponse, error) {
+	var first *WebhookResponse
+	var group []poolWebhook
+	for _, m := range p.members {
+		resp, err := m.Client.CreateWebhook(webhook)
+		if err != nil {
+			for _, created := range group {
+				_ = created.member.Client.DeleteWebhook(created.id)
+			}
+			return nil, err
+		}
+		if first == nil {
+			first = resp
+		}
+		group = append(group, poolWebhook{member: m, id: resp.WebhookID})
+	}
+
+	p.mu.Lock()
+	p.webhooks[first.WebhookID] = group
+	p.mu.Unlock()
+	return first, nil
+}
+
+func (p *ClientPool) DeleteWebhook(webhookID string) error {
+	p.mu.Lock()
+	group, ok := p.webhooks[webhookID]
+	p.mu.Unlock()
+
+	if !ok {
+		return p.probe(p.members, func(c *Client) error { return c.DeleteWebhook(webhookID) }, nil)
+	}
+
+	for _, created := range group {
+		if err := created.member.Client.DeleteWebhook(created.id); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	p.mu.Lock()
+	delete(p.webhooks, webhookID)
+	p.mu.Unlock()
+	return nil
+}
+
+// ObserveWebhook forwards the payload to the member owning the task, or to
+// every member when the owner is unknown.
+func (p *ClientPool) ObserveWebhook(payload *WebhookPayload) {
+	if payload == nil {
+		return
+	}
+
+	var taskID string
+	if payload.TaskDetail != nil {
+		taskID, _ = payload.TaskDetail["task_id"].(string)
+		if credits, ok := payload.TaskDetail["credit_usage"].(float64); ok && taskID != "" {
+			p.mu.Lock()
+			if m, ok := p.tasks[taskID]; ok {
+				m.credits[taskID] = credits
+			}
+			p.mu.Unlock()
+		}
+	}
+
+	if m := p.taskOwner(taskID); m != nil {
+		m.Client.ObserveWebhook(payload)
+		return
+	}
+	for _, m := range p.members {
+		m.Client.ObserveWebhook(payload)
+	}
+}
+
+// candidates returns every member in the order the strategy prefers them,
+// with members in cooldown moved to the end.
+func (p *ClientPool) candidates() []*poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.config.Now()
+	var ordered []*poolMember
+
+	switch p.config.Strategy {
+	case PoolLeastCredits:
+		ordered = append(ordered, p.members...)
+		for i := 1; i < len(ordered); i++ {
+			for j := i; j > 0 && lessLoaded(ordered[j], ordered[j-1]); j-- {
+				ordered[j], ordered[j-1] = ordered[j-1], ordered[j]
+			}
+		}
+	case PoolWeighted:
+		// Smooth weighted round-robin over the available members.
+		total := 0
+		var best *poolMember
+		for _, m := range p.members {
+			if m.cooldownUntil.After(now) {
+				continue
+			}
+			m.current += m.Weight
+			total += m.Weight
+			if best == nil || m.current > best.current {
+				best = m
+			}
+		}
+		if best != nil {
+			best.current -= total
+			ordered = append(ordered, best)
+		}
+		for _, m := range p.members {
+			if m != best {
+				ordered = append(ordered, m)
+			}
+		}
+	default:
+		for i := range p.members {
+			ordered = append(ordered, p.members[(p.next+i)%len(p.members)])
+		}
+		p.next = (p.next + 1) % len(p.members)
+	}
+
+	var ready, cooling []*poolMember
+	for _, m := range ordered {
+		if m.cooldownUntil.After(now) {
+			cooling = append(cooling, m)
+		} else {
+			ready = append(ready, m)
+		}
+	}
+	return append(ready, cooling...)
+}
+
+func (p *ClientPool) coolDown(m *poolMember) {
+	p.mu.Lock()
+	m.cooldownUntil = p.config.Now().Add(p.config.Cooldown)
+	p.mu.Unlock()
+}
+
+func (p *ClientPool) taskOwner(taskID string) *poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tasks[taskID]
+}
+
+func (p *ClientPool) attachmentOwner(options *TaskOptions) *poolMember {
+	if options == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, fileID := range attachmentFileIDs(options.Attachments) {
+		if owner, ok := p.files[fileID]; ok {
+			return owner
+		}
+	}
+	return nil
+}
+
+func (p *ClientPool) routeTask(taskID string, fn func(c *Client) error) error {
+	if strings.TrimSpace(taskID) == "" {
+		return &ValidationError{Message: "Task ID cannot be empty"}
+	}
+	if m := p.taskOwner(taskID); m != nil {
+		return fn(m.Client)
+	}
+	return p.probe(p.members, fn, func(m *poolMember) { p.tasks[taskID] = m })
+}
+
+func (p *ClientPool) routeFile(fileID string, fn func(c *Client) error) error {
+	if strings.TrimSpace(fileID) == "" {
+		return &ValidationError{Message: "File ID cannot be empty"}
+	}
+	p.mu.Lock()
+	m := p.files[fileID]
+	p.mu.Unlock()
+	if m != nil {
+		return fn(m.Client)
+	}
+	return p.probe(p.members, fn, func(m *poolMember) { p.files[fileID] = m })
+}
+
+// probe tries each member in turn for an ID the pool has not seen, such as a
+// task created before a restart, and remembers the member that knows it.
+func (p *ClientPool) probe(members []*poolMember, fn func(c *Client) error, found func(m *poolMember)) error {
+	var lastErr error
+	for _, m := range members {
+		err := fn(m.Client)
+		if err == nil {
+			if found != nil {
+				p.mu.Lock()
+				found(m)
+				p.mu.Unlock()
+			}
+			return nil
+		}
+		if !isNotFound(err) {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (p *ClientPool) recordCredits(detail *TaskDetail) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m, ok := p.tasks[detail.ID]; ok {
+		m.credits[detail.ID] = detail.CreditUsage
+	}
+}
+
+func (m *poolMember) creditsUsed() float64 {
+	var total float64
+	for _, credits := range m.credits {
+		total += credits
+	}
+	return total
+}
+
+func lessLoaded(a, b *poolMember) bool {
+	ca, cb := a.creditsUsed(), b.creditsUsed()
+	if ca != cb {
+		return ca < cb
+	}
+	return a.tasks < b.tasks
+}
+
+// isQuotaError reports whether err means the account is rate limited, out of
+// credits or behind an open circuit, as opposed to a problem with the request
+// itself.
+func isQuotaError(err error) bool {
+	var circuitErr *CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		return true
+	}
+
+	var apiErr *ManusAIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusPaymentRequired {
+		return true
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "quota") || strings.Contains(message, "insufficient credit")
+}
+
+func isNotFound(err error) bool {
+	var apiErr *ManusAIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
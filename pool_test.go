@@ -0,0 +1,263 @@
+package manusai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAccount is a minimal task API for one account.
+type fakeAccount struct {
+	name     string
+	mu       sync.Mutex
+	tasks    map[string]float64
+	limited  bool
+	created  int
+	server   *httptest.Server
+	webhooks []string
+}
+
+func newFakeAccount(t *testing.T, name string) *fakeAccount {
+	a := &fakeAccount{name: name, tasks: make(map[string]float64)}
+	a.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/tasks":
+			if a.limited {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"rate limited"}`))
+				return
+			}
+			a.created++
+			id := fmt.Sprintf("%s_task_%d", a.name, a.created)
+			a.tasks[id] = float64(a.created * 10)
+			json.NewEncoder(w).Encode(TaskResponse{TaskID: id})
+		case r.Method == "GET" && r.URL.Path == "/v1/tasks":
+			var ids []string
+			for id := range a.tasks {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			if after := r.URL.Query().Get("after"); after != "" {
+				i := sort.SearchStrings(ids, after)
+				if i == len(ids) || ids[i] != after {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				ids = ids[i+1:]
+			}
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			var page TaskListResponse
+			if limit > 0 && len(ids) > limit {
+				ids, page.HasMore = ids[:limit], true
+			}
+			for _, id := range ids {
+				page.Data = append(page.Data, TaskSummary{ID: id})
+			}
+			json.NewEncoder(w).Encode(page)
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/tasks/"):
+			id := strings.TrimPrefix(r.URL.Path, "/v1/tasks/")
+			credits, ok := a.tasks[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(TaskDetail{ID: id, Status: TaskStatusCompleted, CreditUsage: credits})
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/tasks/"):
+			id := strings.TrimPrefix(r.URL.Path, "/v1/tasks/")
+			if _, ok := a.tasks[id]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(a.tasks, id)
+			w.Write([]byte(`{"deleted":true}`))
+		case r.Method == "POST" && r.URL.Path == "/v1/webhooks":
+			id := fmt.Sprintf("%s_hook_%d", a.name, len(a.webhooks))
+			a.webhooks = append(a.webhooks, id)
+			json.NewEncoder(w).Encode(WebhookResponse{WebhookID: id})
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/webhooks/"):
+			a.webhooks = nil
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(a.server.Close)
+	return a
+}
+
+func (a *fakeAccount) member(t *testing.T, weight int) PoolMember {
+	client, err := NewClient("key-"+a.name, WithBaseURL(a.server.URL))
+	require.NoError(t, err)
+	return PoolMember{Name: a.name, Client: client, Weight: weight}
+}
+
+func TestClientPoolRoutesByOwner(t *testing.T) {
+	a, b := newFakeAccount(t, "a"), newFakeAccount(t, "b")
+	pool, err := NewClientPool(ClientPoolConfig{Members: []PoolMember{a.member(t, 1), b.member(t, 1)}})
+	require.NoError(t, err)
+
+	first, err := pool.CreateTask("one", nil)
+	require.NoError(t, err)
+	second, err := pool.CreateTask("two", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a_task_1", first.TaskID)
+	assert.Equal(t, "b_task_1", second.TaskID)
+
+	owner, ok := pool.Owner(second.TaskID)
+	assert.True(t, ok)
+	assert.Equal(t, "b", owner)
+
+	detail, err := pool.GetTask(second.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), detail.CreditUsage)
+
+	deleted, err := pool.DeleteTask(first.TaskID)
+	require.NoError(t, err)
+	assert.True(t, deleted.Deleted)
+
+	// A fresh pool finds tasks it did not create by asking each member.
+	fresh, err := NewClientPool(ClientPoolConfig{Members: []PoolMember{a.member(t, 1), b.member(t, 1)}})
+	require.NoError(t, err)
+	_, err = fresh.GetTask(second.TaskID)
+	require.NoError(t, err)
+	owner, _ = fresh.Owner(second.TaskID)
+	assert.Equal(t, "b", owner)
+
+	_, err = fresh.GetTask("missing")
+	assert.True(t, isNotFound(err))
+}
+
+func TestClientPoolAvoidsRateLimitedMember(t *testing.T) {
+	a, b := newFakeAccount(t, "a"), newFakeAccount(t, "b")
+	a.limited = true
+
+	now := time.Unix(1700000000, 0)
+	pool, err := NewClientPool(ClientPoolConfig{
+		Members:  []PoolMember{a.member(t, 1), b.member(t, 1)},
+		Cooldown: time.Minute,
+		Now:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, err := pool.CreateTask("work", nil)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resp.TaskID, "b_"))
+	}
+
+	stats := pool.Stats()
+	assert.Equal(t, now.Add(time.Minute), stats[0].CooldownUntil)
+	assert.Equal(t, 3, stats[1].Tasks)
+
+	b.mu.Lock()
+	b.limited = true
+	b.mu.Unlock()
+	_, err = pool.CreateTask("work", nil)
+	assert.True(t, isQuotaError(err))
+}
+
+func TestClientPoolStrategies(t *testing.T) {
+	t.Run("least credits", func(t *testing.T) {
+		a, b := newFakeAccount(t, "a"), newFakeAccount(t, "b")
+		pool, err := NewClientPool(ClientPoolConfig{
+			Members:  []PoolMember{a.member(t, 1), b.member(t, 1)},
+			Strategy: PoolLeastCredits,
+		})
+		require.NoError(t, err)
+
+		resp, err := pool.CreateTask("one", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "a_task_1", resp.TaskID)
+
+		pool.ObserveWebhook(&WebhookPayload{EventType: WebhookEventTaskStopped, TaskDetail: map[string]interface{}{
+			"task_id": resp.TaskID, "credit_usage": float64(50),
+		}})
+
+		resp, err = pool.CreateTask("two", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "b_task_1", resp.TaskID)
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		a, b := newFakeAccount(t, "a"), newFakeAccount(t, "b")
+		pool, err := NewClientPool(ClientPoolConfig{
+			Members:  []PoolMember{a.member(t, 3), b.member(t, 1)},
+			Strategy: PoolWeighted,
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 8; i++ {
+			_, err := pool.CreateTask("work", nil)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 6, a.created)
+		assert.Equal(t, 2, b.created)
+	})
+
+	_, err := NewClientPool(ClientPoolConfig{Members: []PoolMember{{Client: &Client{}}}, Strategy: "random"})
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestClientPoolWebhooks(t *testing.T) {
+	a, b := newFakeAccount(t, "a"), newFakeAccount(t, "b")
+	pool, err := NewClientPool(ClientPoolConfig{Members: []PoolMember{a.member(t, 1), b.member(t, 1)}})
+	require.NoError(t, err)
+
+	resp, err := pool.CreateWebhook(&WebhookConfig{URL: "https://example.com/webhook"})
+	require.NoError(t, err)
+	assert.Len(t, a.webhooks, 1)
+	assert.Len(t, b.webhooks, 1)
+
+	require.NoError(t, pool.DeleteWebhook(resp.WebhookID))
+	assert.Empty(t, a.webhooks)
+	assert.Empty(t, b.webhooks)
+}
+
+func TestClientPoolGetTasksPagesEveryMember(t *testing.T) {
+	a, b := newFakeAccount(t, "a"), newFakeAccount(t, "b")
+	for i := 1; i <= 3; i++ {
+		a.tasks[fmt.Sprintf("a_task_%d", i)] = 0
+	}
+	for i := 1; i <= 5; i++ {
+		b.tasks[fmt.Sprintf("b_task_%d", i)] = 0
+	}
+	pool, err := NewClientPool(ClientPoolConfig{Members: []PoolMember{a.member(t, 1), b.member(t, 1)}})
+	require.NoError(t, err)
+
+	var ids []string
+	filters := &TaskFilters{Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+		page, err := pool.GetTasks(filters)
+		require.NoError(t, err)
+		for _, summary := range page.Data {
+			ids = append(ids, summary.ID)
+		}
+		if !page.HasMore {
+			break
+		}
+		filters.After = page.Data[len(page.Data)-1].ID
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"a_task_1", "a_task_2", "a_task_3", "b_task_1", "b_task_2", "b_task_3", "b_task_4", "b_task_5"}, ids)
+
+	owner, ok := pool.Owner("a_task_3")
+	assert.True(t, ok)
+	assert.Equal(t, "a", owner)
+
+	_, err = pool.GetTasks(&TaskFilters{After: "a_task_1"})
+	assert.IsType(t, &ValidationError{}, err, "not the end of a pool page")
+}
@@ -0,0 +1,134 @@
+package manusai
+
+import "unicode/utf8"
+
+// ProfileRequest describes a task to a ProfileSelector.
+type ProfileRequest struct {
+	Prompt      string
+	Attachments int
+	Tags        []string
+	// MaxCredits is TaskOptions.MaxCredits; zero means no budget.
+	MaxCredits float64
+}
+
+// ProfileSelector picks the agent profile for tasks created without an
+// explicit TaskOptions.AgentProfile.
+type ProfileSelector interface {
+	SelectProfile(req ProfileRequest) string
+	// Escalate returns a stronger profile to retry req with after a task
+	// using profile failed, or false if there is none.
+	Escalate(req ProfileRequest, profile string) (string, bool)
+}
+
+type ProfileRule struct {
+	// MinPromptLength is counted in characters, not bytes.
+	MinPromptLength int
+	MinAttachments  int
+	// Tags matches tasks with any of these tags.
+	Tags    []string
+	Profile string
+}
+
+func (r ProfileRule) matches(req ProfileRequest) bool {
+	if utf8.RuneCountInString(req.Prompt) < r.MinPromptLength || req.Attachments < r.MinAttachments {
+		return false
+	}
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, want := range r.Tags {
+		for _, tag := range req.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RuleProfileSelector starts from the weakest profile on the ladder and
+// moves up to the strongest profile of any matching rule, then back down
+// until the profile fits the task's credit budget.
+type RuleProfileSelector struct {
+	// Ladder lists profiles from weakest to strongest. It defaults to lite,
+	// standard and max, and is also the escalation order.
+	Ladder []string
+	// Rules whose profile is not on the ladder are ignored.
+	Rules []ProfileRule
+	// MinCredits is the budget a profile needs; a task whose MaxCredits is
+	// set and lower gets a weaker profile.
+	MinCredits map[string]float64
+}
+
+// DefaultProfileSelector uses the standard profile for long prompts or any
+// attachments and max for very long prompts or many attachments.
+func DefaultProfileSelector() *RuleProfileSelector {
+	return &RuleProfileSelector{
+		Rules: []ProfileRule{
+			{MinPromptLength: 2000, Profile: AgentProfileManus16},
+			{MinAttachments: 1, Profile: AgentProfileManus16},
+			{MinPromptLength: 8000, Profile: AgentProfileManus16Max},
+			{MinAttachments: 5, Profile: AgentProfileManus16Max},
+		},
+	}
+}
+
+func (s *RuleProfileSelector) ladder() []string {
+	if len(s.Ladder) > 0 {
+		return s.Ladder
+	}
+	return []string{AgentProfileManus16Lite, AgentProfileManus16, AgentProfileManus16Max}
+}
+
+func (s *RuleProfileSelector) SelectProfile(req ProfileRequest) string {
+	ladder := s.ladder()
+
+	level := 0
+	for _, rule := range s.Rules {
+		if !rule.matches(req) {
+			continue
+		}
+		if i := indexOf(ladder, rule.Profile); i > level {
+			level = i
+		}
+	}
+
+	if req.MaxCredits > 0 {
+		for level > 0 && s.MinCredits[ladder[level]] > req.MaxCredits {
+			level--
+		}
+	}
+	return ladder[level]
+}
+
+// Escalate moves one step up the ladder, within the task's credit budget.
+func (s *RuleProfileSelector) Escalate(req ProfileRequest, profile string) (string, bool) {
+	ladder := s.ladder()
+	i := indexOf(ladder, profile)
+	if i < 0 || i+1 >= len(ladder) {
+		return "", false
+	}
+	next := ladder[i+1]
+	if req.MaxCredits > 0 && s.MinCredits[next] > req.MaxCredits {
+		return "", false
+	}
+	return next, true
+}
+
+func newProfileRequest(prompt string, options *TaskOptions) ProfileRequest {
+	return ProfileRequest{
+		Prompt:      prompt,
+		Attachments: len(options.Attachments),
+		Tags:        options.Tags,
+		MaxCredits:  options.MaxCredits,
+	}
+}
+
+func indexOf(values []string, s string) int {
+	for i, v := range values {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,166 @@
+package manusai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleProfileSelector(t *testing.T) {
+	selector := DefaultProfileSelector()
+	selector.Rules = append(selector.Rules, ProfileRule{Tags: []string{"critical"}, Profile: AgentProfileManus16Max})
+	selector.MinCredits = map[string]float64{AgentProfileManus16Max: 50}
+
+	tests := []struct {
+		name string
+		req  ProfileRequest
+		want string
+	}{
+		{"short prompt", ProfileRequest{Prompt: "Hi"}, AgentProfileManus16Lite},
+		{"long prompt", ProfileRequest{Prompt: strings.Repeat("x", 2000)}, AgentProfileManus16},
+		{"multibyte prompt", ProfileRequest{Prompt: strings.Repeat("é", 1500)}, AgentProfileManus16Lite},
+		{"attachment", ProfileRequest{Prompt: "Hi", Attachments: 1}, AgentProfileManus16},
+		{"many attachments", ProfileRequest{Prompt: "Hi", Attachments: 5}, AgentProfileManus16Max},
+		{"tag", ProfileRequest{Prompt: "Hi", Tags: []string{"nightly", "critical"}}, AgentProfileManus16Max},
+		{"over budget", ProfileRequest{Prompt: "Hi", Attachments: 5, MaxCredits: 20}, AgentProfileManus16},
+		{"within budget", ProfileRequest{Prompt: "Hi", Attachments: 5, MaxCredits: 50}, AgentProfileManus16Max},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, selector.SelectProfile(tt.req))
+		})
+	}
+
+	next, ok := selector.Escalate(ProfileRequest{}, AgentProfileManus16Lite)
+	assert.True(t, ok)
+	assert.Equal(t, AgentProfileManus16, next)
+	_, ok = selector.Escalate(ProfileRequest{MaxCredits: 10}, AgentProfileManus16)
+	assert.False(t, ok, "max is over budget")
+	_, ok = selector.Escalate(ProfileRequest{}, AgentProfileManus16Max)
+	assert.False(t, ok)
+	_, ok = selector.Escalate(ProfileRequest{}, "custom")
+	assert.False(t, ok)
+}
+
+// newEscalationServer fails every task created with a profile in failing.
+func newEscalationServer(t *testing.T, failing map[string]bool) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var profiles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == "POST" {
+			var body struct {
+				AgentProfile string `json:"agentProfile"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			profiles = append(profiles, body.AgentProfile)
+			fmt.Fprintf(w, `{"task_id":"task_%d"}`, len(profiles))
+			return
+		}
+		var n int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/v1/tasks/"), "task_%d", &n)
+		status := TaskStatusCompleted
+		if failing[profiles[n-1]] {
+			status = TaskStatusFailed
+		}
+		fmt.Fprintf(w, `{"id":"task_%d","status":"%s"}`, n, status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &profiles
+}
+
+func TestCreateTaskRecordsSelectedProfile(t *testing.T) {
+	server, profiles := newEscalationServer(t, nil)
+	client, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	resp, err := client.CreateTask("Hi", &TaskOptions{ProfileSelector: DefaultProfileSelector()})
+	require.NoError(t, err)
+	assert.Equal(t, AgentProfileManus16Lite, resp.AgentProfile)
+
+	resp, err = client.CreateTask("Hi", &TaskOptions{AgentProfile: AgentProfileManus16Max, ProfileSelector: DefaultProfileSelector()})
+	require.NoError(t, err)
+	assert.Equal(t, AgentProfileManus16Max, resp.AgentProfile)
+
+	resp, err = client.CreateTask("Hi", nil)
+	require.NoError(t, err)
+	assert.Equal(t, AgentProfileManus16, resp.AgentProfile)
+
+	assert.Equal(t, []string{AgentProfileManus16Lite, AgentProfileManus16Max, AgentProfileManus16}, *profiles)
+}
+
+func TestAwaitEscalatesFailedTasks(t *testing.T) {
+	server, profiles := newEscalationServer(t, map[string]bool{AgentProfileManus16Lite: true, AgentProfileManus16: true})
+	client, err := NewClient("test-key", WithBaseURL(server.URL),
+		WithAwaitConfig(AwaitConfig{WebhookTimeout: time.Millisecond, PollInterval: time.Millisecond}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	handle, err := client.Submit(ctx, "Hi", &TaskOptions{ProfileSelector: DefaultProfileSelector()})
+	require.NoError(t, err)
+
+	detail, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, detail.Status)
+	assert.Equal(t, "task_3", detail.ID)
+	assert.Equal(t, []string{AgentProfileManus16Lite, AgentProfileManus16, AgentProfileManus16Max}, *profiles)
+
+	var used []string
+	for _, attempt := range handle.Attempts() {
+		used = append(used, attempt.AgentProfile)
+	}
+	assert.Equal(t, *profiles, used)
+
+	again, err := handle.Await(ctx)
+	require.NoError(t, err)
+	assert.Same(t, detail, again)
+	assert.Len(t, *profiles, 3)
+}
+
+func TestAwaitStopsAtTopOfLadder(t *testing.T) {
+	server, profiles := newEscalationServer(t, map[string]bool{AgentProfileManus16Max: true})
+	client, err := NewClient("test-key", WithBaseURL(server.URL),
+		WithAwaitConfig(AwaitConfig{WebhookTimeout: time.Millisecond, PollInterval: time.Millisecond}))
+	require.NoError(t, err)
+
+	handle, err := client.Submit(context.Background(), "Hi", &TaskOptions{AgentProfile: AgentProfileManus16Max, ProfileSelector: DefaultProfileSelector()})
+	require.NoError(t, err)
+
+	detail, err := handle.Await(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusFailed, detail.Status)
+	assert.Len(t, *profiles, 1)
+	assert.Len(t, handle.Attempts(), 1)
+}
+
+func TestAwaitEscalatesFromRequestedProfile(t *testing.T) {
+	server, profiles := newEscalationServer(t, map[string]bool{AgentProfileManus16Lite: true})
+	client, err := NewClient("test-key", WithBaseURL(server.URL), WithLogger(nil),
+		WithDeprecationPolicy(DeprecationPolicy{Mode: DeprecationMigrate}),
+		WithAwaitConfig(AwaitConfig{WebhookTimeout: time.Millisecond, PollInterval: time.Millisecond}))
+	require.NoError(t, err)
+
+	// The ladder names the deprecated profiles that are migrated on submit.
+	selector := &RuleProfileSelector{Ladder: []string{AgentProfileSpeed, AgentProfileQuality}}
+	handle, err := client.Submit(context.Background(), "Hi", &TaskOptions{ProfileSelector: selector})
+	require.NoError(t, err)
+	assert.Equal(t, AgentProfileManus16Lite, handle.Response.AgentProfile)
+
+	detail, err := handle.Await(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, detail.Status)
+	assert.Len(t, *profiles, 2)
+	assert.Len(t, handle.Attempts(), 2)
+}
@@ -0,0 +1,313 @@
+package manusai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRelayQueueSize   = 1000
+	DefaultRelayMaxAttempts = 8
+	DefaultRelayBackoff     = time.Second
+	DefaultRelayMaxBackoff  = 5 * time.Minute
+
+	RelayEventTypeHeader = "X-Manus-Event-Type"
+)
+
+type RelayDestination struct {
+	Name string
+	URL  string
+	// Events limits delivery to these event types; empty means all.
+	Events []string
+	// Tags limits delivery to tasks whose TaskStore record carries at least
+	// one of these tags or "key=value" labels; empty means all tasks.
+	Tags        []string
+	Headers     map[string]string
+	QueueSize   int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+type WebhookRelayConfig struct {
+	Destinations []RelayDestination
+	Verifier     WebhookVerifier
+	TaskStore    TaskStore
+	HTTPClient   *http.Client
+	OnError      func(destination string, err error)
+}
+
+type RelayMetrics struct {
+	Delivered    uint64    `json:"delivered"`
+	Failed       uint64    `json:"failed"`
+	Dropped      uint64    `json:"dropped"`
+	Filtered     uint64    `json:"filtered"`
+	Retries      uint64    `json:"retries"`
+	Queued       int       `json:"queued"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastDelivery time.Time `json:"last_delivery,omitempty"`
+}
+
+// WebhookRelay verifies incoming webhook deliveries and fans them out to
+// internal HTTP endpoints. Each destination has its own queue and retry
+// schedule, so a slow consumer does not hold up the others.
+type WebhookRelay struct {
+	config       WebhookRelayConfig
+	destinations []*relayDestination
+}
+
+type relayDestination struct {
+	RelayDestination
+	queue chan *relayDelivery
+
+	mu      sync.Mutex
+	metrics RelayMetrics
+}
+
+type relayDelivery struct {
+	eventType string
+	body      []byte
+}
+
+func NewWebhookRelay(config WebhookRelayConfig) (*WebhookRelay, error) {
+	if len(config.Destinations) == 0 {
+		return nil, &ValidationError{Message: "Webhook relay needs at least one destination"}
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	relay := &WebhookRelay{config: config}
+	seen := make(map[string]bool)
+	for i, dest := range config.Destinations {
+		if dest.URL == "" {
+			return nil, &ValidationError{Message: fmt.Sprintf("Relay destination %d has no URL", i)}
+		}
+		if dest.Name == "" {
+			dest.Name = dest.URL
+		}
+		if seen[dest.Name] {
+			return nil, &ValidationError{Message: fmt.Sprintf("Duplicate relay destination name %q", dest.Name)}
+		}
+		seen[dest.Name] = true
+
+		if dest.QueueSize <= 0 {
+			dest.QueueSize = DefaultRelayQueueSize
+		}
+		if dest.MaxAttempts <= 0 {
+			dest.MaxAttempts = DefaultRelayMaxAttempts
+		}
+		if dest.Backoff <= 0 {
+			dest.Backoff = DefaultRelayBackoff
+		}
+		if dest.MaxBackoff <= 0 {
+			dest.MaxBackoff = DefaultRelayMaxBackoff
+		}
+
+		relay.destinations = append(relay.destinations, &relayDestination{
+			RelayDestination: dest,
+			queue:            make(chan *relayDelivery, dest.QueueSize),
+		})
+	}
+
+	return relay, nil
+}
+
+// Start runs one delivery worker per destination until ctx is cancelled.
+func (r *WebhookRelay) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, dest := range r.destinations {
+		wg.Add(1)
+		go func(dest *relayDestination) {
+			defer wg.Done()
+			r.run(ctx, dest)
+		}(dest)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (r *WebhookRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, status := readWebhookRequest(req, r.config.Verifier)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	payload, err := ParseWebhookPayload(body)
+	if err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	tags := r.taskTags(payload)
+	delivery := &relayDelivery{eventType: payload.EventType, body: body}
+
+	for _, dest := range r.destinations {
+		if !dest.accepts(payload.EventType, tags) {
+			dest.update(func(m *RelayMetrics) { m.Filtered++ })
+			continue
+		}
+
+		dest.update(func(m *RelayMetrics) { m.Queued++ })
+		select {
+		case dest.queue <- delivery:
+		default:
+			dest.update(func(m *RelayMetrics) {
+				m.Queued--
+				m.Dropped++
+			})
+			r.reportError(dest.Name, fmt.Errorf("queue full, dropped %s event", payload.EventType))
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *WebhookRelay) Metrics() map[string]RelayMetrics {
+	result := make(map[string]RelayMetrics, len(r.destinations))
+	for _, dest := range r.destinations {
+		dest.mu.Lock()
+		result[dest.Name] = dest.metrics
+		dest.mu.Unlock()
+	}
+	return result
+}
+
+func (r *WebhookRelay) run(ctx context.Context, dest *relayDestination) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-dest.queue:
+			r.deliver(ctx, dest, delivery)
+		}
+	}
+}
+
+func (r *WebhookRelay) deliver(ctx context.Context, dest *relayDestination, delivery *relayDelivery) {
+	backoff := dest.Backoff
+	for attempt := 1; ; attempt++ {
+		err := r.post(ctx, dest, delivery)
+		if err == nil {
+			dest.update(func(m *RelayMetrics) {
+				m.Queued--
+				m.Delivered++
+				m.LastDelivery = time.Now()
+			})
+			return
+		}
+
+		r.reportError(dest.Name, err)
+		dest.update(func(m *RelayMetrics) { m.LastError = err.Error() })
+
+		if attempt >= dest.MaxAttempts || ctx.Err() != nil {
+			dest.update(func(m *RelayMetrics) {
+				m.Queued--
+				m.Failed++
+			})
+			return
+		}
+
+		dest.update(func(m *RelayMetrics) { m.Retries++ })
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		if backoff *= 2; backoff > dest.MaxBackoff {
+			backoff = dest.MaxBackoff
+		}
+	}
+}
+
+func (r *WebhookRelay) post(ctx context.Context, dest *relayDestination, delivery *relayDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", dest.URL, bytes.NewReader(delivery.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(RelayEventTypeHeader, delivery.eventType)
+	for k, v := range dest.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("destination returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (r *WebhookRelay) taskTags(payload *WebhookPayload) map[string]bool {
+	if r.config.TaskStore == nil || payload.TaskDetail == nil {
+		return nil
+	}
+
+	taskID, _ := payload.TaskDetail["task_id"].(string)
+	if taskID == "" {
+		return nil
+	}
+
+	record, err := r.config.TaskStore.Get(taskID)
+	if err != nil || record == nil {
+		return nil
+	}
+
+	tags := make(map[string]bool, len(record.Tags)+len(record.Labels))
+	for _, tag := range record.Tags {
+		tags[tag] = true
+	}
+	for k, v := range record.Labels {
+		tags[k+"="+v] = true
+	}
+	return tags
+}
+
+func (r *WebhookRelay) reportError(destination string, err error) {
+	if r.config.OnError != nil {
+		r.config.OnError(destination, err)
+	}
+}
+
+func (d *relayDestination) accepts(eventType string, tags map[string]bool) bool {
+	if len(d.Events) > 0 {
+		found := false
+		for _, e := range d.Events {
+			if e == eventType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(d.Tags) > 0 {
+		for _, tag := range d.Tags {
+			if tags[tag] {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func (d *relayDestination) update(fn func(m *RelayMetrics)) {
+	d.mu.Lock()
+	fn(&d.metrics)
+	d.mu.Unlock()
+}
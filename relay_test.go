@@ -0,0 +1,126 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRelayFiltersAndRetries(t *testing.T) {
+	allEvents := make(chan string, 10)
+	all := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "secret", r.Header.Get("X-Relay-Token"))
+		allEvents <- r.Header.Get(RelayEventTypeHeader) + " " + string(body)
+	}))
+	defer all.Close()
+
+	var flakyCalls int32
+	billingEvents := make(chan string, 10)
+	billing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&flakyCalls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		billingEvents <- string(body)
+	}))
+	defer billing.Close()
+
+	store := NewMemoryTaskStore()
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_billing", Tags: []string{"billing"}}))
+
+	relay, err := NewWebhookRelay(WebhookRelayConfig{
+		TaskStore: store,
+		Destinations: []RelayDestination{
+			{Name: "all", URL: all.URL, Headers: map[string]string{"X-Relay-Token": "secret"}},
+			{
+				Name:    "billing",
+				URL:     billing.URL,
+				Events:  []string{WebhookEventTaskStopped},
+				Tags:    []string{"billing"},
+				Backoff: time.Millisecond,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Start(ctx)
+
+	created := `{"event_type":"task_created","task_detail":{"task_id":"task_billing"}}`
+	stoppedOther := `{"event_type":"task_stopped","task_detail":{"task_id":"task_other"}}`
+	stoppedBilling := `{"event_type":"task_stopped","task_detail":{"task_id":"task_billing"}}`
+	for _, body := range []string{created, stoppedOther, stoppedBilling} {
+		assert.Equal(t, http.StatusOK, postInbox(t, relay, body))
+	}
+
+	for _, want := range []string{"task_created " + created, "task_stopped " + stoppedOther, "task_stopped " + stoppedBilling} {
+		select {
+		case got := <-allEvents:
+			assert.Equal(t, want, got)
+		case <-time.After(2 * time.Second):
+			t.Fatal("event not relayed")
+		}
+	}
+
+	select {
+	case got := <-billingEvents:
+		assert.Equal(t, stoppedBilling, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("billing event not relayed")
+	}
+
+	require.Eventually(t, func() bool { return relay.Metrics()["billing"].Delivered == 1 }, time.Second, 5*time.Millisecond)
+	metrics := relay.Metrics()
+	assert.Equal(t, uint64(3), metrics["all"].Delivered)
+	assert.Equal(t, uint64(2), metrics["billing"].Filtered)
+	assert.Equal(t, uint64(2), metrics["billing"].Retries)
+	assert.Equal(t, 0, metrics["billing"].Queued)
+}
+
+func TestWebhookRelayGivesUp(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	relay, err := NewWebhookRelay(WebhookRelayConfig{
+		Destinations: []RelayDestination{{URL: down.URL, MaxAttempts: 2, Backoff: time.Millisecond}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Start(ctx)
+
+	assert.Equal(t, http.StatusOK, postInbox(t, relay, `{"event_type":"task_created","task_detail":{"task_id":"task_1"}}`))
+
+	require.Eventually(t, func() bool { return relay.Metrics()[down.URL].Failed == 1 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, relay.Metrics()[down.URL].LastError, "status 500")
+}
+
+func TestWebhookRelayRejectsUnverified(t *testing.T) {
+	relay, err := NewWebhookRelay(WebhookRelayConfig{
+		Destinations: []RelayDestination{{URL: "http://127.0.0.1:0"}},
+		Verifier: WebhookVerifierFunc(func(r *http.Request, body []byte) error {
+			return errors.New("bad signature")
+		}),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, postInbox(t, relay, `{"event_type":"task_created"}`))
+	assert.Equal(t, 0, relay.Metrics()["http://127.0.0.1:0"].Queued)
+
+	_, err = NewWebhookRelay(WebhookRelayConfig{})
+	assert.Error(t, err)
+}
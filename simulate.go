@@ -0,0 +1,243 @@
+package manusai
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SimulateOptions struct {
+	// Signer, when set, adds Manus-style signature headers to each delivery.
+	Signer *WebhookSigner
+	// SignURL is the URL included in the signature. Defaults to the target URL.
+	SignURL    string
+	HTTPClient *http.Client
+}
+
+type SimulationStep struct {
+	Delay time.Duration
+	Event *WebhookPayload
+}
+
+// WebhookSigner signs payloads the way Manus does, for use with a local key
+// pair in tests and development.
+type WebhookSigner struct {
+	PrivateKey *rsa.PrivateKey
+	Now        func() time.Time
+}
+
+func NewWebhookSigner(privateKeyPEM []byte) (*WebhookSigner, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, &ValidationError{Message: "Webhook private key is not PEM encoded"}
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return &WebhookSigner{PrivateKey: key}, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, &ValidationError{Message: fmt.Sprintf("Invalid webhook private key: %v", err), Err: err}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, &ValidationError{Message: fmt.Sprintf("Webhook private key has unsupported type %T", key)}
+	}
+	return &WebhookSigner{PrivateKey: rsaKey}, nil
+}
+
+func (s *WebhookSigner) Sign(req *http.Request, url string, body []byte) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.PrivateKey, crypto.SHA256, webhookSigningDigest(timestamp, url, body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, base64.StdEncoding.EncodeToString(sig))
+	return nil
+}
+
+func NewTaskCreatedEvent(taskID, title, taskURL string) *WebhookPayload {
+	return &WebhookPayload{
+		EventID:   "evt_" + randomID(),
+		EventType: WebhookEventTaskCreated,
+		TaskDetail: map[string]interface{}{
+			"task_id":    taskID,
+			"task_title": title,
+			"task_url":   taskURL,
+		},
+	}
+}
+
+func NewTaskStoppedEvent(taskID, stopReason, message string, attachments []OutputAttachment) *WebhookPayload {
+	detail := map[string]interface{}{
+		"task_id":     taskID,
+		"stop_reason": stopReason,
+		"message":     message,
+	}
+
+	if len(attachments) > 0 {
+		items := make([]interface{}, len(attachments))
+		for i, att := range attachments {
+			item := map[string]interface{}{
+				"file_name": att.FileName,
+				"url":       att.URL,
+			}
+			if att.SizeBytes > 0 {
+				item["size_bytes"] = float64(att.SizeBytes)
+			}
+			items[i] = item
+		}
+		detail["attachments"] = items
+	}
+
+	return &WebhookPayload{
+		EventID:    "evt_" + randomID(),
+		EventType:  WebhookEventTaskStopped,
+		TaskDetail: detail,
+	}
+}
+
+// NewTaskStoppedEventFromDetail builds a task_stopped payload from a real
+// task, using its last assistant message and its attachments.
+func NewTaskStoppedEventFromDetail(detail *TaskDetail, stopReason string) *WebhookPayload {
+	var message string
+	for i := len(detail.Output) - 1; i >= 0; i-- {
+		if detail.Output[i].Role == "assistant" {
+			message = detail.Output[i].Content
+			break
+		}
+	}
+
+	payload := NewTaskStoppedEvent(detail.ID, stopReason, message, detail.Attachments)
+	payload.TaskDetail["task_title"] = detail.Title
+	if detail.CreditUsage > 0 {
+		payload.TaskDetail["credit_usage"] = detail.CreditUsage
+	}
+	return payload
+}
+
+func SimulateWebhook(url string, event *WebhookPayload) error {
+	return SimulateWebhookWithOptions(context.Background(), url, event, nil)
+}
+
+func SimulateWebhookWithOptions(ctx context.Context, url string, event *WebhookPayload, options *SimulateOptions) error {
+	if event == nil {
+		return &ValidationError{Message: "Webhook event cannot be nil"}
+	}
+
+	var opts SimulateOptions
+	if options != nil {
+		opts = *options
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return &ManusAIError{Message: fmt.Sprintf("Failed to marshal webhook event: %v", err), Err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return &ManusAIError{Message: fmt.Sprintf("Failed to create webhook request: %v", err), Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if opts.Signer != nil {
+		signURL := opts.SignURL
+		if signURL == "" {
+			signURL = url
+		}
+		if err := opts.Signer.Sign(req, signURL, body); err != nil {
+			return &ManusAIError{Message: fmt.Sprintf("Failed to sign webhook event: %v", err), Err: err}
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &ManusAIError{Message: fmt.Sprintf("Webhook delivery failed: %v", err), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &ManusAIError{
+			Message:    fmt.Sprintf("Webhook endpoint returned status %d: %s", resp.StatusCode, string(respBody)),
+			StatusCode: resp.StatusCode,
+		}
+	}
+
+	return nil
+}
+
+// SimulateWebhookSequence delivers each step's event after waiting for its
+// delay, stopping at the first failed delivery.
+func SimulateWebhookSequence(ctx context.Context, url string, steps []SimulationStep, options *SimulateOptions) error {
+	for i, step := range steps {
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := SimulateWebhookWithOptions(ctx, url, step.Event, options); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// ParseSimulationScript reads a JSON array of steps such as
+//
+//	[{"delay": "2s", "event": {"event_type": "task_created", "task_detail": {...}}}]
+func ParseSimulationScript(r io.Reader) ([]SimulationStep, error) {
+	var raw []struct {
+		Delay string          `json:"delay"`
+		Event *WebhookPayload `json:"event"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid simulation script: %w", err)
+	}
+
+	steps := make([]SimulationStep, len(raw))
+	for i, item := range raw {
+		if item.Event == nil || item.Event.EventType == "" {
+			return nil, fmt.Errorf("simulation step %d: missing event_type", i+1)
+		}
+		if item.Delay != "" {
+			delay, err := time.ParseDuration(item.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("simulation step %d: %w", i+1, err)
+			}
+			steps[i].Delay = delay
+		}
+		if item.Event.EventID == "" {
+			item.Event.EventID = "evt_" + randomID()
+		}
+		steps[i].Event = item.Event
+	}
+	return steps, nil
+}
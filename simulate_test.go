@@ -0,0 +1,98 @@
+package manusai
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateWebhookSigned(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := NewWebhookSigner(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var received []*WebhookPayload
+	verifier := &RSAWebhookVerifier{PublicKey: &key.PublicKey, URL: "https://example.com/hook"}
+	server := httptest.NewServer(NewWebhookHandler(func(payload *WebhookPayload) {
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+	}, WithWebhookVerifier(verifier)))
+	defer server.Close()
+
+	event := NewTaskStoppedEvent("task_1", StopReasonFinish, "Done", []OutputAttachment{
+		{FileName: "report.pdf", URL: "https://files.example.com/report.pdf", SizeBytes: 1024},
+	})
+
+	err = SimulateWebhook(server.URL, event)
+	assert.IsType(t, &ManusAIError{}, err)
+
+	err = SimulateWebhookWithOptions(context.Background(), server.URL, event, &SimulateOptions{
+		Signer:  signer,
+		SignURL: "https://example.com/hook",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, received, 1)
+	assert.True(t, IsTaskCompleted(received[0]))
+	assert.Equal(t, event.EventID, received[0].EventID)
+	assert.Equal(t, []OutputAttachment{{FileName: "report.pdf", URL: "https://files.example.com/report.pdf", SizeBytes: 1024}},
+		GetOutputAttachments(received[0]))
+}
+
+func TestNewTaskStoppedEventFromDetail(t *testing.T) {
+	payload := NewTaskStoppedEventFromDetail(&TaskDetail{
+		ID:          "task_1",
+		Title:       "Report",
+		CreditUsage: 2,
+		Output: []TaskMessage{
+			{Role: "assistant", Content: "Working on it"},
+			{Role: "assistant", Content: "Here it is"},
+			{Role: "user", Content: "Thanks"},
+		},
+	}, StopReasonAsk)
+
+	assert.True(t, IsTaskAskingForInput(payload))
+	assert.Equal(t, "Here it is", payload.TaskDetail["message"])
+	assert.Equal(t, "Report", payload.TaskDetail["task_title"])
+}
+
+func TestSimulateWebhookSequence(t *testing.T) {
+	steps, err := ParseSimulationScript(strings.NewReader(`[
+		{"event": {"event_type": "task_created", "task_detail": {"task_id": "task_1"}}},
+		{"delay": "10ms", "event": {"event_type": "task_stopped", "task_detail": {"task_id": "task_1", "stop_reason": "finish"}}}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.NotEmpty(t, steps[0].Event.EventID)
+
+	var types []string
+	server := httptest.NewServer(NewWebhookHandler(func(payload *WebhookPayload) {
+		types = append(types, payload.EventType)
+	}))
+	defer server.Close()
+
+	require.NoError(t, SimulateWebhookSequence(context.Background(), server.URL, steps, nil))
+	assert.Equal(t, []string{WebhookEventTaskCreated, WebhookEventTaskStopped}, types)
+
+	_, err = ParseSimulationScript(strings.NewReader(`[{"delay": "soon", "event": {"event_type": "task_created"}}]`))
+	assert.Error(t, err)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	assert.Error(t, SimulateWebhookSequence(context.Background(), failing.URL, steps, nil))
+}
@@ -0,0 +1,258 @@
+package manusai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+type TaskRecord struct {
+	TaskID      string            `json:"task_id"`
+	PromptHash  string            `json:"prompt_hash,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	StopReason  string            `json:"stop_reason,omitempty"`
+	CreditUsage float64           `json:"credit_usage,omitempty"`
+	FileIDs     []string          `json:"file_ids,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func (r *TaskRecord) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TaskRecord) clone() *TaskRecord {
+	cp := *r
+	cp.Tags = append([]string(nil), r.Tags...)
+	cp.FileIDs = append([]string(nil), r.FileIDs...)
+	if r.Labels != nil {
+		cp.Labels = make(map[string]string, len(r.Labels))
+		for k, v := range r.Labels {
+			cp.Labels[k] = v
+		}
+	}
+	return &cp
+}
+
+// TaskQuery selects records whose status is one of Status (if set), that
+// carry every tag in Tags and whose labels match every entry in Labels.
+type TaskQuery struct {
+	Status []string
+	Tags   []string
+	Labels map[string]string
+}
+
+func (q TaskQuery) Matches(r *TaskRecord) bool {
+	if len(q.Status) > 0 {
+		found := false
+		for _, s := range q.Status {
+			if r.Status == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tag := range q.Tags {
+		if !r.HasTag(tag) {
+			return false
+		}
+	}
+	for k, v := range q.Labels {
+		if got, ok := r.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// TaskStore keeps local metadata about tasks. Get returns a nil record and a
+// nil error when the task is unknown; Update is a no-op in that case.
+type TaskStore interface {
+	Put(record *TaskRecord) error
+	Get(taskID string) (*TaskRecord, error)
+	Update(taskID string, fn func(record *TaskRecord)) error
+	Delete(taskID string) error
+	Find(query TaskQuery) ([]*TaskRecord, error)
+}
+
+func WithTaskStore(store TaskStore) ClientOption {
+	return func(c *Client) {
+		c.taskStore = store
+	}
+}
+
+func HashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+type MemoryTaskStore struct {
+	mu      sync.RWMutex
+	records map[string]*TaskRecord
+}
+
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{records: make(map[string]*TaskRecord)}
+}
+
+func (s *MemoryTaskStore) Put(record *TaskRecord) error {
+	if record == nil || record.TaskID == "" {
+		return &ValidationError{Message: "Task record must have a task ID"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.TaskID] = record.clone()
+	return nil
+}
+
+func (s *MemoryTaskStore) Get(taskID string) (*TaskRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[taskID]
+	if !ok {
+		return nil, nil
+	}
+	return record.clone(), nil
+}
+
+func (s *MemoryTaskStore) Update(taskID string, fn func(record *TaskRecord)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[taskID]; ok {
+		fn(record)
+	}
+	return nil
+}
+
+func (s *MemoryTaskStore) Delete(taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, taskID)
+	return nil
+}
+
+func (s *MemoryTaskStore) Find(query TaskQuery) ([]*TaskRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*TaskRecord
+	for _, record := range s.records {
+		if query.Matches(record) {
+			result = append(result, record.clone())
+		}
+	}
+	sortTaskRecords(result)
+	return result, nil
+}
+
+// FileTaskStore is an embedded TaskStore that keeps all records in memory and
+// rewrites a single JSON file atomically on every change.
+type FileTaskStore struct {
+	mem  *MemoryTaskStore
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileTaskStore(path string) (*FileTaskStore, error) {
+	store := &FileTaskStore{mem: NewMemoryTaskStore(), path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*TaskRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid task store file %s: %w", path, err)
+	}
+	for _, record := range records {
+		store.mem.records[record.TaskID] = record
+	}
+
+	return store, nil
+}
+
+func (s *FileTaskStore) Put(record *TaskRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.mem.Put(record); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileTaskStore) Get(taskID string) (*TaskRecord, error) {
+	return s.mem.Get(taskID)
+}
+
+// Update rewrites the file only if the task is known and fn changed it.
+func (s *FileTaskStore) Update(taskID string, fn func(record *TaskRecord)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	err := s.mem.Update(taskID, func(record *TaskRecord) {
+		before := record.clone()
+		fn(record)
+		changed = !reflect.DeepEqual(before, record)
+	})
+	if err != nil || !changed {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileTaskStore) Delete(taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.mem.Delete(taskID); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileTaskStore) Find(query TaskQuery) ([]*TaskRecord, error) {
+	return s.mem.Find(query)
+}
+
+func (s *FileTaskStore) flush() error {
+	records, _ := s.mem.Find(TaskQuery{})
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+func sortTaskRecords(records []*TaskRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].TaskID < records[j].TaskID
+	})
+}
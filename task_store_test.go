@@ -0,0 +1,145 @@
+package manusai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskQueryMatches(t *testing.T) {
+	record := &TaskRecord{
+		TaskID: "task_1",
+		Status: TaskStatusRunning,
+		Tags:   []string{"nightly"},
+		Labels: map[string]string{"customer": "42", "pipeline": "etl"},
+	}
+
+	assert.True(t, TaskQuery{}.Matches(record))
+	assert.True(t, TaskQuery{Status: []string{TaskStatusRunning}, Labels: map[string]string{"customer": "42"}}.Matches(record))
+	assert.True(t, TaskQuery{Tags: []string{"nightly"}}.Matches(record))
+	assert.False(t, TaskQuery{Status: []string{TaskStatusCompleted}}.Matches(record))
+	assert.False(t, TaskQuery{Labels: map[string]string{"customer": "7"}}.Matches(record))
+	assert.False(t, TaskQuery{Tags: []string{"adhoc"}}.Matches(record))
+}
+
+func TestMemoryTaskStore(t *testing.T) {
+	store := NewMemoryTaskStore()
+
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_1", Status: TaskStatusRunning}))
+	record, err := store.Get("task_1")
+	require.NoError(t, err)
+	record.Status = TaskStatusFailed
+
+	stored, _ := store.Get("task_1")
+	assert.Equal(t, TaskStatusRunning, stored.Status)
+
+	require.NoError(t, store.Update("task_1", func(r *TaskRecord) { r.Status = TaskStatusCompleted }))
+	stored, _ = store.Get("task_1")
+	assert.Equal(t, TaskStatusCompleted, stored.Status)
+
+	missing, err := store.Get("task_2")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, store.Delete("task_1"))
+	records, _ := store.Find(TaskQuery{})
+	assert.Empty(t, records)
+
+	assert.Error(t, store.Put(&TaskRecord{}))
+}
+
+func TestFileTaskStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	store, err := NewFileTaskStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_1", Labels: map[string]string{"customer": "42"}}))
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_2", Labels: map[string]string{"customer": "7"}}))
+
+	reopened, err := NewFileTaskStore(path)
+	require.NoError(t, err)
+	records, err := reopened.Find(TaskQuery{Labels: map[string]string{"customer": "42"}})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "task_1", records[0].TaskID)
+}
+
+func TestFileTaskStoreUpdateSkipsUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	store, err := NewFileTaskStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_1", Status: TaskStatusRunning}))
+	require.NoError(t, os.Remove(path))
+
+	require.NoError(t, store.Update("task_unknown", func(r *TaskRecord) { r.Status = TaskStatusCompleted }))
+	require.NoError(t, store.Update("task_1", func(r *TaskRecord) { r.Status = TaskStatusRunning }))
+	assert.NoFileExists(t, path)
+
+	require.NoError(t, store.Update("task_1", func(r *TaskRecord) { r.Status = TaskStatusCompleted }))
+	assert.FileExists(t, path)
+}
+
+func TestClientWithTaskStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST":
+			body, _ := io.ReadAll(r.Body)
+			var payload map[string]interface{}
+			json.Unmarshal(body, &payload)
+			assert.NotContains(t, payload, "labels")
+			assert.NotContains(t, payload, "tags")
+			w.Write([]byte(`{"task_id":"task_123"}`))
+		case r.URL.Path == "/v1/tasks":
+			w.Write([]byte(`{"data":[{"id":"task_123","status":"running"},{"id":"task_999","status":"running"}]}`))
+		default:
+			w.Write([]byte(`{"id":"task_123","status":"completed","credit_usage":3}`))
+		}
+	}))
+	defer server.Close()
+
+	store := NewMemoryTaskStore()
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL), WithTaskStore(store))
+
+	_, err := client.CreateTask("Analyze", &TaskOptions{
+		Tags:        []string{"nightly"},
+		Labels:      map[string]string{"customer": "42"},
+		Attachments: []interface{}{NewAttachmentFromFileID("file_1")},
+	})
+	require.NoError(t, err)
+
+	record, _ := store.Get("task_123")
+	require.NotNil(t, record)
+	assert.Equal(t, HashPrompt("Analyze"), record.PromptHash)
+	assert.Equal(t, []string{"file_1"}, record.FileIDs)
+	assert.Equal(t, TaskStatusPending, record.Status)
+
+	_, err = client.GetTasks(nil)
+	require.NoError(t, err)
+	running, _ := store.Find(TaskQuery{Status: []string{TaskStatusRunning}, Labels: map[string]string{"customer": "42"}})
+	require.Len(t, running, 1)
+
+	unknown, _ := store.Get("task_999")
+	assert.Nil(t, unknown)
+
+	_, err = client.GetTask("task_123")
+	require.NoError(t, err)
+	record, _ = store.Get("task_123")
+	assert.Equal(t, TaskStatusCompleted, record.Status)
+	assert.Equal(t, 3.0, record.CreditUsage)
+
+	require.NoError(t, store.Put(&TaskRecord{TaskID: "task_456"}))
+	client.ObserveWebhook(&WebhookPayload{
+		EventType:  WebhookEventTaskStopped,
+		TaskDetail: map[string]interface{}{"task_id": "task_456", "stop_reason": StopReasonAsk},
+	})
+	record, _ = store.Get("task_456")
+	assert.Equal(t, StopReasonAsk, record.StopReason)
+}
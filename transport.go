@@ -0,0 +1,122 @@
+package manusai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultMaxIdleConnsPerHost = 10
+)
+
+// TransportConfig tunes the HTTP transport used for API requests. Zero
+// values keep the defaults.
+type TransportConfig struct {
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	DisableHTTP2          bool
+	// ProxyURL routes requests through an HTTP(S) proxy. When empty, the
+	// HTTP_PROXY/HTTPS_PROXY environment variables apply.
+	ProxyURL string
+	// RootCAs replaces the system roots, e.g. for a TLS-intercepting proxy.
+	RootCAs      *x509.CertPool
+	Certificates []tls.Certificate
+}
+
+// WithTransportConfig applies config on top of the client's transport. A
+// client passed to WithHTTPClient is copied rather than modified.
+func WithTransportConfig(config TransportConfig) ClientOption {
+	return func(c *Client) {
+		c.transportConfig = &config
+	}
+}
+
+func defaultTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   DefaultConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	return transport
+}
+
+// applyTransportConfig returns a copy of httpClient whose transport reflects
+// config.
+func applyTransportConfig(httpClient *http.Client, config *TransportConfig) (*http.Client, error) {
+	var transport *http.Transport
+	switch rt := httpClient.Transport.(type) {
+	case nil:
+		transport = defaultTransport()
+	case *http.Transport:
+		transport = rt.Clone()
+	default:
+		return nil, &ValidationError{Message: fmt.Sprintf("Transport config requires an *http.Transport, got %T", rt)}
+	}
+
+	if config.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   config.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+
+	if config.ProxyURL != "" {
+		proxy, err := url.Parse(config.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, &ValidationError{Message: fmt.Sprintf("Invalid proxy URL %q", config.ProxyURL), Err: err}
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if config.RootCAs != nil || len(config.Certificates) > 0 {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+		}
+		if config.RootCAs != nil {
+			tlsConfig.RootCAs = config.RootCAs
+		}
+		if len(config.Certificates) > 0 {
+			tlsConfig.Certificates = config.Certificates
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if config.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	clone := *httpClient
+	clone.Transport = transport
+	return &clone, nil
+}
@@ -0,0 +1,98 @@
+package manusai
+
+import (
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDefaultTransport(t *testing.T) {
+	client, err := NewClient("test-key")
+	require.NoError(t, err)
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, DefaultTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+}
+
+func TestWithTransportConfigDoesNotMutateUserClient(t *testing.T) {
+	userClient := &http.Client{Timeout: 5 * time.Second}
+	client, err := NewClient("test-key",
+		WithTransportConfig(TransportConfig{ResponseHeaderTimeout: 7 * time.Second, MaxIdleConnsPerHost: 50, DisableHTTP2: true}),
+		WithHTTPClient(userClient),
+	)
+	require.NoError(t, err)
+
+	assert.Nil(t, userClient.Transport)
+	assert.NotSame(t, userClient, client.httpClient)
+	assert.Equal(t, 5*time.Second, client.httpClient.Timeout)
+
+	transport := client.httpClient.Transport.(*http.Transport)
+	assert.Equal(t, 7*time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 50, transport.MaxIdleConnsPerHost)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+
+	_, err = NewClient("test-key",
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}),
+		WithTransportConfig(TransportConfig{}),
+	)
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = NewClient("test-key", WithTransportConfig(TransportConfig{ProxyURL: "::not a url"}))
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestWithTransportConfigProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient("test-key",
+		WithBaseURL("http://api.manus.test"),
+		WithTransportConfig(TransportConfig{ProxyURL: proxy.URL}),
+	)
+	require.NoError(t, err)
+
+	_, err = client.ListFiles()
+	require.NoError(t, err)
+	assert.Equal(t, "api.manus.test", proxiedHost)
+}
+
+func TestWithTransportConfigRootCAs(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	untrusted, err := NewClient("test-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+	_, err = untrusted.ListFiles()
+	require.Error(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	trusted, err := NewClient("test-key", WithBaseURL(server.URL), WithTransportConfig(TransportConfig{RootCAs: pool}))
+	require.NoError(t, err)
+	_, err = trusted.ListFiles()
+	require.NoError(t, err)
+}
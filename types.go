@@ -1,18 +1,47 @@
 package manusai
 
+const (
+	TaskStatusPending   = "pending"
+	TaskStatusRunning   = "running"
+	TaskStatusCompleted = "completed"
+	TaskStatusFailed    = "failed"
+)
+
 type TaskOptions struct {
-	AgentProfile        string        `json:"agentProfile,omitempty"`
-	TaskMode            string        `json:"taskMode,omitempty"`
-	Locale              string        `json:"locale,omitempty"`
-	HideInTaskList      *bool         `json:"hideInTaskList,omitempty"`
-	CreateShareableLink *bool         `json:"createShareableLink,omitempty"`
-	Attachments         []interface{} `json:"attachments,omitempty"`
+	AgentProfile        string            `json:"agentProfile,omitempty"`
+	TaskMode            string            `json:"taskMode,omitempty"`
+	Locale              string            `json:"locale,omitempty"`
+	HideInTaskList      *bool             `json:"hideInTaskList,omitempty"`
+	CreateShareableLink *bool             `json:"createShareableLink,omitempty"`
+	Attachments         []interface{}     `json:"attachments,omitempty"`
+	Tags                []string          `json:"-"`
+	Labels              map[string]string `json:"-"`
+	// IdempotencyKey is sent as the Idempotency-Key header and remembered
+	// locally, so retrying with the same key never creates a second task.
+	IdempotencyKey string `json:"-"`
+	// WaitForFiles makes CreateTask wait until every file_id attachment is
+	// ready before submitting the task.
+	WaitForFiles bool `json:"-"`
+	// ProfileSelector picks the agent profile when AgentProfile is empty,
+	// and escalates failed tasks awaited through Submit.
+	ProfileSelector ProfileSelector `json:"-"`
+	// MaxCredits is the credit budget passed to ProfileSelector.
+	MaxCredits float64 `json:"-"`
 }
 
 type TaskResponse struct {
 	TaskID    string `json:"task_id"`
 	TaskTitle string `json:"task_title"`
-	TaskURL   string `json:"task_url"`
+	// TaskURL is empty when the creation response was lost and the task
+	// was recovered through an idempotency key.
+	TaskURL string `json:"task_url"`
+	// AgentProfile is the profile the task was created with, after profile
+	// selection and deprecation handling.
+	AgentProfile string `json:"agent_profile,omitempty"`
+
+	// requestedProfile is the profile asked for or selected before
+	// deprecation handling, which is what ProfileSelector ladders name.
+	requestedProfile string
 }
 
 type TaskFilters struct {
@@ -40,13 +69,14 @@ type TaskSummary struct {
 }
 
 type TaskDetail struct {
-	ID          string        `json:"id"`
-	Title       string        `json:"title"`
-	Status      string        `json:"status"`
-	CreditUsage float64       `json:"credit_usage"`
-	Output      []TaskMessage `json:"output"`
-	CreatedAt   string        `json:"created_at"`
-	UpdatedAt   string        `json:"updated_at"`
+	ID          string             `json:"id"`
+	Title       string             `json:"title"`
+	Status      string             `json:"status"`
+	CreditUsage float64            `json:"credit_usage"`
+	Output      []TaskMessage      `json:"output"`
+	Attachments []OutputAttachment `json:"attachments,omitempty"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
 }
 
 type TaskMessage struct {
@@ -55,9 +85,9 @@ type TaskMessage struct {
 }
 
 type TaskUpdate struct {
-	Title                    *string `json:"title,omitempty"`
-	EnableShared             *bool   `json:"enableShared,omitempty"`
-	EnableVisibleInTaskList  *bool   `json:"enableVisibleInTaskList,omitempty"`
+	Title                   *string `json:"title,omitempty"`
+	EnableShared            *bool   `json:"enableShared,omitempty"`
+	EnableVisibleInTaskList *bool   `json:"enableVisibleInTaskList,omitempty"`
 }
 
 type DeleteResponse struct {
@@ -92,7 +122,19 @@ type WebhookResponse struct {
 	WebhookID string `json:"webhook_id"`
 }
 
+type WebhookDetail struct {
+	WebhookID string   `json:"webhook_id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events,omitempty"`
+	CreatedAt string   `json:"created_at,omitempty"`
+}
+
+type WebhookListResponse struct {
+	Data []WebhookDetail `json:"data"`
+}
+
 type WebhookPayload struct {
+	EventID    string                 `json:"event_id,omitempty"`
 	EventType  string                 `json:"event_type"`
 	TaskDetail map[string]interface{} `json:"task_detail,omitempty"`
 }
@@ -104,3 +146,9 @@ type TaskAttachment struct {
 	Data     string `json:"data,omitempty"`
 	MimeType string `json:"mime_type,omitempty"`
 }
+
+type OutputAttachment struct {
+	FileName  string `json:"file_name"`
+	URL       string `json:"url"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+}
@@ -0,0 +1,258 @@
+package manusai
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultUploadTimeout     = 10 * time.Minute
+	DefaultUploadMaxAttempts = 3
+	DefaultUploadBackoff     = time.Second
+	DefaultUploadMaxBackoff  = 30 * time.Second
+)
+
+type ChecksumAlgorithm string
+
+const (
+	ChecksumNone   ChecksumAlgorithm = ""
+	ChecksumMD5    ChecksumAlgorithm = "md5"
+	ChecksumSHA1   ChecksumAlgorithm = "sha1"
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	ChecksumCRC32  ChecksumAlgorithm = "crc32"
+	ChecksumCRC32C ChecksumAlgorithm = "crc32c"
+)
+
+// UploadConfig controls how file content is PUT to pre-signed storage URLs.
+// Uploads use their own HTTP client so large files are not cut off by the
+// API client's timeout.
+type UploadConfig struct {
+	// HTTPClient defaults to the API client's transport without a timeout.
+	HTTPClient *http.Client
+	// Timeout bounds each attempt; negative disables it.
+	Timeout     time.Duration
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Checksum is the default checksum header for every upload.
+	Checksum ChecksumAlgorithm
+}
+
+type UploadOptions struct {
+	ContentType string
+	// Checksum overrides UploadConfig.Checksum for this upload.
+	Checksum ChecksumAlgorithm
+	// Headers are extra headers the pre-signed URL was signed with.
+	Headers map[string]string
+}
+
+func WithUploadConfig(config UploadConfig) ClientOption {
+	return func(c *Client) {
+		c.upload = config
+	}
+}
+
+func (c *Client) UploadFileContent(uploadURL string, fileContent []byte, contentType string) error {
+	return c.UploadFileContentWithOptions(context.Background(), uploadURL, fileContent, &UploadOptions{ContentType: contentType})
+}
+
+func (c *Client) UploadFileContentWithOptions(ctx context.Context, uploadURL string, fileContent []byte, options *UploadOptions) error {
+	open := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(fileContent)), nil
+	}
+	return c.UploadFileReader(ctx, uploadURL, open, int64(len(fileContent)), options)
+}
+
+// UploadFilePath streams a local file to uploadURL without loading it into
+// memory.
+func (c *Client) UploadFilePath(ctx context.Context, uploadURL, path string, options *UploadOptions) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return &ValidationError{Message: fmt.Sprintf("Cannot read upload file: %v", err), Err: err}
+	}
+	open := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	return c.UploadFileReader(ctx, uploadURL, open, info.Size(), options)
+}
+
+// UploadFileReader uploads the content returned by open, which is called
+// again for every attempt (and once more to compute a checksum), so it must
+// return the same bytes each time.
+func (c *Client) UploadFileReader(ctx context.Context, uploadURL string, open func() (io.ReadCloser, error), size int64, options *UploadOptions) error {
+	if strings.TrimSpace(uploadURL) == "" {
+		return &ValidationError{Message: "Upload URL cannot be empty"}
+	}
+
+	var opts UploadOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
+	}
+	if opts.Checksum == ChecksumNone {
+		opts.Checksum = c.upload.Checksum
+	}
+
+	var checksumHeader, checksumValue string
+	if opts.Checksum != ChecksumNone {
+		var err error
+		checksumHeader, checksumValue, err = computeChecksum(opts.Checksum, open)
+		if err != nil {
+			return err
+		}
+	}
+
+	maxAttempts := c.upload.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultUploadMaxAttempts
+	}
+	backoff := c.upload.Backoff
+	if backoff <= 0 {
+		backoff = DefaultUploadBackoff
+	}
+	maxBackoff := c.upload.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultUploadMaxBackoff
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		retryable, err = c.uploadOnce(ctx, uploadURL, open, size, opts, checksumHeader, checksumValue)
+		if err == nil || !retryable || attempt >= maxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// uploadOnce makes a single attempt and reports whether a failure is worth
+// retrying.
+func (c *Client) uploadOnce(ctx context.Context, uploadURL string, open func() (io.ReadCloser, error), size int64, opts UploadOptions, checksumHeader, checksumValue string) (bool, error) {
+	timeout := c.upload.Timeout
+	if timeout == 0 {
+		timeout = DefaultUploadTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// An empty body must be http.NoBody: any other reader with a zero length
+	// is sent chunked, which pre-signed URLs reject.
+	var body io.ReadCloser = http.NoBody
+	if size != 0 {
+		var err error
+		body, err = open()
+		if err != nil {
+			return false, &ManusAIError{Message: fmt.Sprintf("Failed to open upload content: %v", err), Err: err}
+		}
+		defer body.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, body)
+	if err != nil {
+		return false, &ManusAIError{Message: fmt.Sprintf("Failed to create upload request: %v", err)}
+	}
+	req.ContentLength = size
+	req.GetBody = func() (io.ReadCloser, error) {
+		if size == 0 {
+			return http.NoBody, nil
+		}
+		return open()
+	}
+	req.Header.Set("Content-Type", opts.ContentType)
+	if checksumHeader != "" {
+		req.Header.Set(checksumHeader, checksumValue)
+	}
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.uploadClient().Do(req)
+	if err != nil {
+		return true, &ManusAIError{Message: fmt.Sprintf("Failed to upload file content: %v", err), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		retryable := resp.StatusCode == http.StatusRequestTimeout ||
+			resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode >= 500
+		return retryable, &ManusAIError{
+			Message:    fmt.Sprintf("Upload failed with status %d: %s", resp.StatusCode, string(respBody)),
+			StatusCode: resp.StatusCode,
+		}
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	return false, nil
+}
+
+func (c *Client) uploadClient() *http.Client {
+	if c.upload.HTTPClient != nil {
+		return c.upload.HTTPClient
+	}
+	return &http.Client{
+		Transport:     c.httpClient.Transport,
+		CheckRedirect: c.httpClient.CheckRedirect,
+		Jar:           c.httpClient.Jar,
+	}
+}
+
+// computeChecksum returns the header and value for algorithm, using
+// Content-MD5 for MD5 and the S3 x-amz-checksum-* headers otherwise.
+func computeChecksum(algorithm ChecksumAlgorithm, open func() (io.ReadCloser, error)) (string, string, error) {
+	var h hash.Hash
+	var header string
+	switch algorithm {
+	case ChecksumMD5:
+		h, header = md5.New(), "Content-MD5"
+	case ChecksumSHA1:
+		h, header = sha1.New(), "x-amz-checksum-sha1"
+	case ChecksumSHA256:
+		h, header = sha256.New(), "x-amz-checksum-sha256"
+	case ChecksumCRC32:
+		h, header = crc32.NewIEEE(), "x-amz-checksum-crc32"
+	case ChecksumCRC32C:
+		h, header = crc32.New(crc32.MakeTable(crc32.Castagnoli)), "x-amz-checksum-crc32c"
+	default:
+		return "", "", &ValidationError{Message: fmt.Sprintf("Unsupported checksum algorithm %q", algorithm)}
+	}
+
+	body, err := open()
+	if err != nil {
+		return "", "", &ManusAIError{Message: fmt.Sprintf("Failed to open upload content: %v", err), Err: err}
+	}
+	defer body.Close()
+
+	if _, err := io.Copy(h, body); err != nil {
+		return "", "", &ManusAIError{Message: fmt.Sprintf("Failed to read upload content: %v", err), Err: err}
+	}
+
+	return header, base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
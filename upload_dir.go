@@ -0,0 +1,387 @@
+package manusai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DefaultUploadDirConcurrency = 4
+
+type UploadDirOptions struct {
+	// Include and Exclude are glob patterns. A pattern without a slash is
+	// matched against the file name, otherwise against the slash-separated
+	// path relative to dir; "**" matches any number of directories. With no
+	// Include patterns every file is included.
+	Include     []string
+	Exclude     []string
+	Concurrency int
+	// Cache maps a file's path and content hash to its file ID so unchanged
+	// files are not uploaded again. Nil disables the cache.
+	Cache UploadCache
+	// TrustCache reuses cached file IDs without first checking with GetFile
+	// that they still exist. Leave it unset if files may be deleted (for
+	// example by FileJanitor) or the cache is shared between API keys.
+	TrustCache bool
+	Upload     *UploadOptions
+}
+
+// UploadCache stores file IDs by the key UploadDir derives from a file's
+// relative path and SHA-256.
+type UploadCache interface {
+	Get(key string) (fileID string, ok bool, err error)
+	Put(key, fileID string) error
+	Delete(key string) error
+}
+
+type UploadedFile struct {
+	Path      string `json:"path"`
+	FileID    string `json:"file_id"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes"`
+	// Cached is true when the file was not uploaded because the cache
+	// already had it.
+	Cached bool `json:"cached"`
+}
+
+type UploadManifest struct {
+	Files []UploadedFile `json:"files"`
+}
+
+// Attachments returns a file_id attachment per uploaded file, ready for
+// TaskOptions.Attachments.
+func (m *UploadManifest) Attachments() []interface{} {
+	var attachments []interface{}
+	for _, f := range m.Files {
+		attachments = append(attachments, NewAttachmentFromFileID(f.FileID))
+	}
+	return attachments
+}
+
+// UploadDir uploads every matching file under dir in parallel and returns a
+// manifest sorted by path. On error the manifest lists the files uploaded so far.
+func (c *Client) UploadDir(ctx context.Context, dir string, options *UploadDirOptions) (*UploadManifest, error) {
+	var opts UploadDirOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultUploadDirConcurrency
+	}
+
+	files, err := walkUploadDir(dir, opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]UploadedFile, len(files))
+	err = runParallel(ctx, len(files), opts.Concurrency, func(i int) error {
+		filePath := filepath.Join(dir, filepath.FromSlash(files[i]))
+		sum, size, err := hashFile(filePath)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("%s: %w", files[i], err)
+		}
+
+		fileID, cached, err := c.uploadDirFile(ctx, filePath, uploadCacheKey(files[i], sum), &opts)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("%s: %w", files[i], err)
+		}
+		results[i] = UploadedFile{Path: files[i], FileID: fileID, SHA256: sum, SizeBytes: size, Cached: cached}
+		return nil
+	})
+
+	manifest := &UploadManifest{}
+	for _, f := range results {
+		if f.FileID != "" {
+			manifest.Files = append(manifest.Files, f)
+		}
+	}
+	return manifest, err
+}
+
+// uploadCacheKey keys a file by its relative path as well as its content, so
+// identical files under different names each get their own upload.
+func uploadCacheKey(rel, sum string) string {
+	return sum + ":" + rel
+}
+
+func (c *Client) uploadDirFile(ctx context.Context, filePath, key string, opts *UploadDirOptions) (string, bool, error) {
+	if opts.Cache != nil {
+		fileID, ok, err := opts.Cache.Get(key)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			if opts.TrustCache {
+				return fileID, true, nil
+			}
+			if _, err := c.getFile(ctx, fileID); err == nil {
+				return fileID, true, nil
+			} else if !isNotFound(err) {
+				return "", false, err
+			}
+			_ = opts.Cache.Delete(key)
+		}
+	}
+
+	file, err := c.createFile(ctx, filepath.Base(filePath), nil)
+	if err != nil {
+		return "", false, err
+	}
+
+	var uploadOpts UploadOptions
+	if opts.Upload != nil {
+		uploadOpts = *opts.Upload
+	}
+	if uploadOpts.ContentType == "" {
+		uploadOpts.ContentType = mime.TypeByExtension(filepath.Ext(filePath))
+	}
+	if err := c.UploadFilePath(ctx, file.UploadURL, filePath, &uploadOpts); err != nil {
+		return "", false, err
+	}
+
+	if opts.Cache != nil {
+		if err := opts.Cache.Put(key, file.ID); err != nil {
+			return "", false, err
+		}
+	}
+	return file.ID, false, nil
+}
+
+// runParallel calls fn for 0..n-1 on up to workers goroutines and returns the
+// first error, after which no new calls are started and ctx is expected to
+// be cancelled by the caller.
+func runParallel(ctx context.Context, n, workers int, fn func(i int) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		next     int
+	)
+
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if firstErr != nil || next >= n {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+
+				err := ctx.Err()
+				if err == nil {
+					err = fn(i)
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+func walkUploadDir(dir string, include, exclude []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && matchAnyGlob(exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if matchAnyGlob(exclude, rel) {
+			return nil
+		}
+		if len(include) > 0 && !matchAnyGlob(include, rel) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, &ValidationError{Message: fmt.Sprintf("Cannot read upload directory: %v", err), Err: err}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func matchAnyGlob(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated relative path against pattern. A
+// pattern without a slash matches the base name at any depth.
+func matchGlob(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func hashFile(filePath string) (string, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+type MemoryUploadCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+func NewMemoryUploadCache() *MemoryUploadCache {
+	return &MemoryUploadCache{entries: make(map[string]string)}
+}
+
+func (m *MemoryUploadCache) Get(key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fileID, ok := m.entries[key]
+	return fileID, ok, nil
+}
+
+func (m *MemoryUploadCache) Put(key, fileID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = fileID
+	return nil
+}
+
+func (m *MemoryUploadCache) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// FileUploadCache persists the key-to-file-ID map as a JSON object.
+type FileUploadCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+func NewFileUploadCache(path string) (*FileUploadCache, error) {
+	c := &FileUploadCache{path: path, entries: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("invalid upload cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *FileUploadCache) Get(key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fileID, ok := c.entries[key]
+	return fileID, ok, nil
+}
+
+func (c *FileUploadCache) Put(key, fileID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = fileID
+	return c.saveLocked()
+}
+
+func (c *FileUploadCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return c.saveLocked()
+}
+
+func (c *FileUploadCache) saveLocked() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, data)
+}
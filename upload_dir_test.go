@@ -0,0 +1,185 @@
+package manusai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFileAPI struct {
+	mu       sync.Mutex
+	server   *httptest.Server
+	created  int
+	checked  int
+	uploaded map[string]string
+	deleted  map[string]bool
+	// failUploads makes every PUT fail with 403.
+	failUploads bool
+}
+
+func newFakeFileAPI(t *testing.T) *fakeFileAPI {
+	f := &fakeFileAPI{uploaded: make(map[string]string), deleted: make(map[string]bool)}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/files":
+			var body struct {
+				Filename string `json:"filename"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			f.mu.Lock()
+			f.created++
+			id := fmt.Sprintf("file_%d", f.created)
+			f.mu.Unlock()
+			json.NewEncoder(w).Encode(FileResponse{ID: id, Filename: body.Filename, UploadURL: f.server.URL + "/upload/" + id})
+		case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/upload/"):
+			data, _ := io.ReadAll(r.Body)
+			f.mu.Lock()
+			if f.failUploads {
+				f.mu.Unlock()
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			f.uploaded[strings.TrimPrefix(r.URL.Path, "/upload/")] = string(data)
+			f.mu.Unlock()
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/files/"):
+			id := strings.TrimPrefix(r.URL.Path, "/v1/files/")
+			f.mu.Lock()
+			f.checked++
+			deleted := f.deleted[id]
+			f.mu.Unlock()
+			if deleted {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(FileDetail{ID: id, Status: string(FileStatusReady)})
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/files/"):
+			f.mu.Lock()
+			f.deleted[strings.TrimPrefix(r.URL.Path, "/v1/files/")] = true
+			f.mu.Unlock()
+			w.Write([]byte(`{"deleted":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func writeTree(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestMatchGlob(t *testing.T) {
+	assert.True(t, matchGlob("*.csv", "data/2024/sales.csv"))
+	assert.True(t, matchGlob("data/**/*.csv", "data/2024/q1/sales.csv"))
+	assert.True(t, matchGlob("data/**/*.csv", "data/sales.csv"))
+	assert.False(t, matchGlob("data/*.csv", "data/2024/sales.csv"))
+	assert.True(t, matchGlob("tmp", "a/tmp"))
+}
+
+func TestUploadDir(t *testing.T) {
+	api := newFakeFileAPI(t)
+	dir := writeTree(t, map[string]string{
+		"sales.csv":         "a,b\n1,2\n",
+		"copy/sales.csv":    "a,b\n1,2\n",
+		"report.pdf":        "%PDF",
+		"notes.txt":         "skip me",
+		"tmp/scratch.csv":   "excluded",
+		"nested/deep/x.csv": "x",
+	})
+
+	client, err := NewClient("test-key", WithBaseURL(api.server.URL))
+	require.NoError(t, err)
+
+	cache := NewMemoryUploadCache()
+	opts := &UploadDirOptions{
+		Include:     []string{"*.csv", "*.pdf"},
+		Exclude:     []string{"tmp"},
+		Concurrency: 3,
+		Cache:       cache,
+	}
+
+	manifest, err := client.UploadDir(context.Background(), dir, opts)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, f := range manifest.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"copy/sales.csv", "nested/deep/x.csv", "report.pdf", "sales.csv"}, paths)
+	// Identical content under another path is still its own attachment.
+	assert.NotEqual(t, manifest.Files[0].FileID, manifest.Files[3].FileID)
+	assert.Equal(t, 4, api.created)
+	assert.Len(t, manifest.Attachments(), 4)
+	assert.Equal(t, "%PDF", api.uploaded[manifest.Files[2].FileID])
+
+	// A second run finds everything in the cache after checking it exists.
+	again, err := client.UploadDir(context.Background(), dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 4, api.created)
+	assert.Equal(t, 4, api.checked)
+	for _, f := range again.Files {
+		assert.True(t, f.Cached)
+	}
+}
+
+func TestUploadDirReuploadsDeletedCachedFile(t *testing.T) {
+	api := newFakeFileAPI(t)
+	dir := writeTree(t, map[string]string{"sales.csv": "a,b\n1,2\n"})
+
+	client, err := NewClient("test-key", WithBaseURL(api.server.URL))
+	require.NoError(t, err)
+
+	opts := &UploadDirOptions{Cache: NewMemoryUploadCache()}
+	first, err := client.UploadDir(context.Background(), dir, opts)
+	require.NoError(t, err)
+	api.deleted[first.Files[0].FileID] = true
+
+	second, err := client.UploadDir(context.Background(), dir, opts)
+	require.NoError(t, err)
+	assert.False(t, second.Files[0].Cached)
+	assert.NotEqual(t, first.Files[0].FileID, second.Files[0].FileID)
+	assert.Equal(t, 2, api.created)
+
+	// TrustCache skips the check.
+	opts.TrustCache = true
+	third, err := client.UploadDir(context.Background(), dir, opts)
+	require.NoError(t, err)
+	assert.True(t, third.Files[0].Cached)
+	assert.Equal(t, 1, api.checked)
+}
+
+func TestFileUploadCachePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploads.json")
+	cache, err := NewFileUploadCache(path)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("abc", "file_1"))
+
+	reopened, err := NewFileUploadCache(path)
+	require.NoError(t, err)
+	fileID, ok, err := reopened.Get("abc")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "file_1", fileID)
+
+	require.NoError(t, reopened.Delete("abc"))
+	_, ok, _ = reopened.Get("abc")
+	assert.False(t, ok)
+}
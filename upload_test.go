@@ -0,0 +1,121 @@
+package manusai
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadFileContentRetriesAndAccepts2xx(t *testing.T) {
+	var attempts int32
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = body
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithUploadConfig(UploadConfig{Backoff: time.Millisecond}))
+	require.NoError(t, err)
+
+	require.NoError(t, client.UploadFileContent(server.URL, []byte("hello"), "text/plain"))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.Equal(t, "hello", string(received))
+}
+
+func TestUploadFileContentDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithUploadConfig(UploadConfig{Backoff: time.Millisecond}))
+	require.NoError(t, err)
+
+	err = client.UploadFileContent(server.URL, []byte("hello"), "")
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestUploadIgnoresAPITimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+
+	assert.NoError(t, client.UploadFileContent(server.URL, []byte("slow"), ""))
+}
+
+func TestUploadChecksums(t *testing.T) {
+	content := []byte("checksum me")
+	headers := make(chan http.Header, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", WithUploadConfig(UploadConfig{Checksum: ChecksumMD5}))
+	require.NoError(t, err)
+
+	require.NoError(t, client.UploadFileContent(server.URL, content, ""))
+	md5Sum := md5.Sum(content)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(md5Sum[:]), (<-headers).Get("Content-MD5"))
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	require.NoError(t, client.UploadFilePath(context.Background(), server.URL, path, &UploadOptions{
+		Checksum: ChecksumSHA256,
+		Headers:  map[string]string{"x-amz-sdk-checksum-algorithm": "SHA256"},
+	}))
+	sha := sha256.Sum256(content)
+	h := <-headers
+	assert.Equal(t, base64.StdEncoding.EncodeToString(sha[:]), h.Get("x-amz-checksum-sha256"))
+	assert.Equal(t, "SHA256", h.Get("x-amz-sdk-checksum-algorithm"))
+	assert.Empty(t, h.Get("Content-MD5"))
+
+	err = client.UploadFileContentWithOptions(context.Background(), server.URL, content, &UploadOptions{Checksum: "md4"})
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestUploadEmptyContentSendsContentLength(t *testing.T) {
+	var contentLength int64 = -2
+	var chunked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		chunked = r.TransferEncoding
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key")
+	require.NoError(t, err)
+
+	require.NoError(t, client.UploadFileContent(server.URL, []byte{}, "text/plain"))
+	assert.Equal(t, int64(0), contentLength)
+	assert.Empty(t, chunked)
+}
@@ -3,6 +3,16 @@ package manusai
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	WebhookEventTaskCreated = "task_created"
+	WebhookEventTaskStopped = "task_stopped"
+
+	StopReasonFinish = "finish"
+	StopReasonAsk    = "ask"
 )
 
 func ParseWebhookPayload(jsonPayload []byte) (*WebhookPayload, error) {
@@ -18,6 +28,68 @@ func ParseWebhookPayload(jsonPayload []byte) (*WebhookPayload, error) {
 	return &payload, nil
 }
 
+const maxWebhookBodyBytes = 10 << 20
+
+type WebhookHandlerOption func(*webhookHandlerConfig)
+
+type webhookHandlerConfig struct {
+	verifier WebhookVerifier
+}
+
+// WithWebhookVerifier rejects deliveries that fail verification with 401.
+func WithWebhookVerifier(verifier WebhookVerifier) WebhookHandlerOption {
+	return func(c *webhookHandlerConfig) {
+		c.verifier = verifier
+	}
+}
+
+// NewWebhookHandler returns an http.Handler that parses incoming webhook
+// deliveries and passes them to fn before acknowledging with 200 OK.
+func NewWebhookHandler(fn func(payload *WebhookPayload), opts ...WebhookHandlerOption) http.Handler {
+	var config webhookHandlerConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, status := readWebhookRequest(r, config.verifier)
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		payload, err := ParseWebhookPayload(body)
+		if err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		fn(payload)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// readWebhookRequest reads and verifies a delivery, returning the raw body
+// and the HTTP status to answer with when it is not acceptable.
+func readWebhookRequest(r *http.Request, verifier WebhookVerifier) ([]byte, int) {
+	if r.Method != http.MethodPost {
+		return nil, http.StatusMethodNotAllowed
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+
+	if verifier != nil {
+		if err := verifier.Verify(r, body); err != nil {
+			return nil, http.StatusUnauthorized
+		}
+	}
+
+	return body, http.StatusOK
+}
+
 func IsTaskCreated(payload *WebhookPayload) bool {
 	return payload.EventType == "task_created"
 }
@@ -68,3 +140,28 @@ func GetAttachments(payload *WebhookPayload) []interface{} {
 
 	return attachments
 }
+
+func GetOutputAttachments(payload *WebhookPayload) []OutputAttachment {
+	raw := GetAttachments(payload)
+	if raw == nil {
+		return nil
+	}
+
+	result := make([]OutputAttachment, 0, len(raw))
+	for _, item := range raw {
+		attMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var att OutputAttachment
+		att.FileName, _ = attMap["file_name"].(string)
+		att.URL, _ = attMap["url"].(string)
+		if size, ok := attMap["size_bytes"].(float64); ok {
+			att.SizeBytes = int64(size)
+		}
+		result = append(result, att)
+	}
+
+	return result
+}
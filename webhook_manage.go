@@ -0,0 +1,79 @@
+package manusai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnsureWebhook reconciles the account's webhooks for url: it creates one if
+// none exists, updates the first match if its events have drifted, and
+// deletes any further webhooks registered for the same URL.
+func (c *Client) EnsureWebhook(ctx context.Context, url string, events []string) (*WebhookDetail, error) {
+	if strings.TrimSpace(url) == "" {
+		return nil, &ValidationError{Message: "Webhook URL is required"}
+	}
+
+	list, err := c.listWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []WebhookDetail
+	for _, webhook := range list.Data {
+		if sameWebhookURL(webhook.URL, url) {
+			matches = append(matches, webhook)
+		}
+	}
+
+	config := &WebhookConfig{URL: url, Events: events}
+
+	if len(matches) == 0 {
+		created, err := c.createWebhook(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return &WebhookDetail{WebhookID: created.WebhookID, URL: url, Events: events}, nil
+	}
+
+	keep := matches[0]
+	var errs []error
+	for _, duplicate := range matches[1:] {
+		if err := c.deleteWebhook(ctx, duplicate.WebhookID); err != nil {
+			errs = append(errs, fmt.Errorf("delete duplicate webhook %s: %w", duplicate.WebhookID, err))
+		}
+	}
+
+	if keep.URL != url || !sameWebhookEvents(keep.Events, events) {
+		updated, err := c.updateWebhook(ctx, keep.WebhookID, config)
+		if err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		keep = *updated
+	}
+
+	return &keep, errors.Join(errs...)
+}
+
+func sameWebhookURL(a, b string) bool {
+	return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+func sameWebhookEvents(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	x := append([]string(nil), a...)
+	y := append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package manusai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhookAPI struct {
+	mu       sync.Mutex
+	webhooks []WebhookDetail
+	calls    []string
+}
+
+func (f *fakeWebhookAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	id := strings.TrimPrefix(r.URL.Path, "/v1/webhooks/")
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(WebhookListResponse{Data: f.webhooks})
+	case "POST", "PATCH":
+		body, _ := io.ReadAll(r.Body)
+		var payload struct {
+			Webhook WebhookConfig `json:"webhook"`
+		}
+		json.Unmarshal(body, &payload)
+		detail := WebhookDetail{WebhookID: id, URL: payload.Webhook.URL, Events: payload.Webhook.Events}
+		if r.Method == "POST" {
+			detail.WebhookID = "webhook_new"
+			f.webhooks = append(f.webhooks, detail)
+			json.NewEncoder(w).Encode(WebhookResponse{WebhookID: detail.WebhookID})
+			return
+		}
+		for i := range f.webhooks {
+			if f.webhooks[i].WebhookID == id {
+				f.webhooks[i] = detail
+			}
+		}
+		json.NewEncoder(w).Encode(detail)
+	case "DELETE":
+		for i := range f.webhooks {
+			if f.webhooks[i].WebhookID == id {
+				f.webhooks = append(f.webhooks[:i], f.webhooks[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestEnsureWebhook(t *testing.T) {
+	events := []string{WebhookEventTaskCreated, WebhookEventTaskStopped}
+
+	t.Run("creates when missing", func(t *testing.T) {
+		api := &fakeWebhookAPI{}
+		server := httptest.NewServer(api)
+		defer server.Close()
+		client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+
+		result, err := client.EnsureWebhook(context.Background(), "https://example.com/hook", events)
+		require.NoError(t, err)
+		assert.Equal(t, "webhook_new", result.WebhookID)
+
+		_, err = client.EnsureWebhook(context.Background(), "https://example.com/hook", []string{WebhookEventTaskStopped, WebhookEventTaskCreated})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GET /v1/webhooks", "POST /v1/webhooks", "GET /v1/webhooks"}, api.calls)
+	})
+
+	t.Run("updates drift and removes duplicates", func(t *testing.T) {
+		api := &fakeWebhookAPI{webhooks: []WebhookDetail{
+			{WebhookID: "webhook_1", URL: "https://example.com/hook", Events: []string{WebhookEventTaskStopped}},
+			{WebhookID: "webhook_2", URL: "https://example.com/hook/", Events: events},
+			{WebhookID: "webhook_3", URL: "https://other.example.com/hook", Events: events},
+		}}
+		server := httptest.NewServer(api)
+		defer server.Close()
+		client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+
+		result, err := client.EnsureWebhook(context.Background(), "https://example.com/hook", events)
+		require.NoError(t, err)
+		assert.Equal(t, "webhook_1", result.WebhookID)
+		assert.Equal(t, events, result.Events)
+
+		require.Len(t, api.webhooks, 2)
+		assert.Equal(t, "webhook_3", api.webhooks[1].WebhookID)
+		assert.Contains(t, api.calls, "DELETE /v1/webhooks/webhook_2")
+		assert.Contains(t, api.calls, "PATCH /v1/webhooks/webhook_1")
+	})
+}
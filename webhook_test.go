@@ -1,6 +1,9 @@
 package manusai
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -136,3 +139,22 @@ func TestGetAttachments(t *testing.T) {
 		assert.Nil(t, result)
 	})
 }
+
+func TestNewWebhookHandler(t *testing.T) {
+	var received *WebhookPayload
+	handler := NewWebhookHandler(func(payload *WebhookPayload) { received = payload })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook", strings.NewReader(`{"event_type":"task_created"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, received)
+	assert.Equal(t, WebhookEventTaskCreated, received.EventType)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/webhook", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,143 @@
+package manusai
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+
+	DefaultWebhookMaxSkew = 5 * time.Minute
+)
+
+type WebhookVerifier interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+type WebhookVerifierFunc func(r *http.Request, body []byte) error
+
+func (f WebhookVerifierFunc) Verify(r *http.Request, body []byte) error {
+	return f(r, body)
+}
+
+// RSAWebhookVerifier checks the RSA-SHA256 signature Manus attaches to webhook
+// deliveries. The signed content is "<timestamp>.<url>.<hex sha256 of body>".
+type RSAWebhookVerifier struct {
+	PublicKey *rsa.PublicKey
+	// URL is the public URL the webhook was registered with. When empty it
+	// is reconstructed from the request, which may be wrong behind a proxy.
+	URL     string
+	MaxSkew time.Duration
+	Now     func() time.Time
+}
+
+func NewRSAWebhookVerifier(publicKeyPEM []byte, url string) (*RSAWebhookVerifier, error) {
+	key, err := ParseWebhookPublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &RSAWebhookVerifier{PublicKey: key, URL: url, MaxSkew: DefaultWebhookMaxSkew}, nil
+}
+
+func (v *RSAWebhookVerifier) Verify(r *http.Request, body []byte) error {
+	signature := r.Header.Get(WebhookSignatureHeader)
+	timestamp := r.Header.Get(WebhookTimestampHeader)
+	if signature == "" || timestamp == "" {
+		return &AuthenticationError{Message: "missing webhook signature headers"}
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return &AuthenticationError{Message: "invalid webhook timestamp", Err: err}
+	}
+
+	maxSkew := v.MaxSkew
+	if maxSkew == 0 {
+		maxSkew = DefaultWebhookMaxSkew
+	}
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if skew := now().Sub(time.Unix(ts, 0)); maxSkew > 0 && (skew > maxSkew || skew < -maxSkew) {
+		return &AuthenticationError{Message: "webhook timestamp outside allowed window"}
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return &AuthenticationError{Message: "invalid webhook signature encoding", Err: err}
+	}
+
+	url := v.URL
+	if url == "" {
+		url = requestURL(r)
+	}
+
+	digest := webhookSigningDigest(timestamp, url, body)
+	if err := rsa.VerifyPKCS1v15(v.PublicKey, crypto.SHA256, digest, sig); err != nil {
+		return &AuthenticationError{Message: "webhook signature mismatch", Err: err}
+	}
+	return nil
+}
+
+func ParseWebhookPublicKey(publicKeyPEM []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, &ValidationError{Message: "Webhook public key is not PEM encoded"}
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, &ValidationError{Message: fmt.Sprintf("Webhook public key has unsupported type %T", key)}
+		}
+		return rsaKey, nil
+	}
+
+	key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, &ValidationError{Message: fmt.Sprintf("Invalid webhook public key: %v", err), Err: err}
+	}
+	return key, nil
+}
+
+func (c *Client) GetWebhookPublicKey() (string, error) {
+	var result struct {
+		PublicKey string `json:"public_key"`
+	}
+	err := c.request("GET", "/v1/webhook/public_key", nil, nil, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result.PublicKey, nil
+}
+
+func webhookSigningDigest(timestamp, url string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	content := timestamp + "." + url + "." + hex.EncodeToString(bodyHash[:])
+	digest := sha256.Sum256([]byte(content))
+	return digest[:]
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
@@ -0,0 +1,78 @@
+package manusai
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signTestRequest(t *testing.T, key *rsa.PrivateKey, req *http.Request, url string, body []byte, ts time.Time) {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, webhookSigningDigest(timestamp, url, body))
+	require.NoError(t, err)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, base64.StdEncoding.EncodeToString(sig))
+}
+
+func TestRSAWebhookVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	url := "https://example.com/webhook"
+	verifier, err := NewRSAWebhookVerifier(pemKey, url)
+	require.NoError(t, err)
+
+	body := []byte(`{"event_type":"task_created"}`)
+
+	t.Run("valid signature", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhook", nil)
+		signTestRequest(t, key, req, url, body, time.Now())
+		assert.NoError(t, verifier.Verify(req, body))
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhook", nil)
+		signTestRequest(t, key, req, url, body, time.Now())
+		err := verifier.Verify(req, []byte(`{"event_type":"task_stopped"}`))
+		assert.IsType(t, &AuthenticationError{}, err)
+	})
+
+	t.Run("stale timestamp", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhook", nil)
+		signTestRequest(t, key, req, url, body, time.Now().Add(-time.Hour))
+		assert.Error(t, verifier.Verify(req, body))
+	})
+
+	t.Run("missing headers", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhook", nil)
+		assert.Error(t, verifier.Verify(req, body))
+	})
+
+	t.Run("handler rejects unverified", func(t *testing.T) {
+		called := false
+		handler := NewWebhookHandler(func(*WebhookPayload) { called = true }, WithWebhookVerifier(verifier))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook", strings.NewReader(string(body))))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.False(t, called)
+	})
+
+	_, err = ParseWebhookPublicKey([]byte("not a key"))
+	assert.Error(t, err)
+}